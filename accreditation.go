@@ -0,0 +1,455 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Accreditor Payload ----- //
+type AccreditorPayload struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	MspId      string   `json:"mspId"`
+	Identities []string `json:"identities"`
+}
+
+var accreditor_schema = PayloadSchema{
+	Name: "accreditor",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of accreditor"},
+		{Name: "name", Type: "string", Required: true, MaxLength: 256, Description: "Name of the accrediting body"},
+		{Name: "mspId", Type: "string", Required: true, MaxLength: 64, Description: "MSP whose members act for the accreditor"},
+		{Name: "identities", Type: "array", Description: "Restrict to these \"<msp>:<common name>\" identities"},
+	},
+}
+
+// ----- Accreditation Payload ----- //
+type AccreditationPayload struct {
+	UniversityId string   `json:"university_id"`
+	Scope        []string `json:"scope"`
+	Act          string   `json:"act"`
+	ValidFrom    string   `json:"valid_from"`
+	ValidUntil   string   `json:"valid_until"`
+}
+
+var accreditation_schema = PayloadSchema{
+	Name: "accreditation",
+	Fields: []FieldSpec{
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the accredited university"},
+		{Name: "scope", Type: "array", Required: true, Description: "Kinds of degrees the university may confer"},
+		{Name: "act", Type: "string", Required: true, MaxLength: 256, Description: "Official act (portaria) of the accreditation"},
+		{Name: "valid_from", Type: "string", Required: true, MaxLength: 10, Description: "First valid day, YYYY-MM-DD"},
+		{Name: "valid_until", Type: "string", Required: true, MaxLength: 10, Description: "Last valid day, YYYY-MM-DD"},
+	},
+}
+
+// ========================================================
+// Get Accreditor - get an accreditor asset from ledger
+// ========================================================
+func get_accreditor(stub shim.ChaincodeStubInterface, id string) (Accreditor, error) {
+	var accreditor Accreditor
+	accreditorKey, _ := stub.CreateCompositeKey("accreditor", []string{id})
+	accreditorAsBytes, err := stub.GetState(accreditorKey)
+	if err != nil {
+		return accreditor, errors.New("Failed to get accreditor - " + id)
+	}
+	if accreditorAsBytes == nil {
+		return accreditor, new_error(CodeNotFound, "Accreditor does not exist - "+id)
+	}
+	err = json.Unmarshal(accreditorAsBytes, &accreditor) //un stringify it aka JSON.parse()
+	if err != nil {
+		return accreditor, errors.New("Accreditor is not readable - " + id)
+	}
+	return accreditor, nil
+}
+
+// ========================================================
+// Get Creator Accreditor - find the accreditor the transaction creator acts for
+// ========================================================
+func get_creator_accreditor(stub shim.ChaincodeStubInterface) (Accreditor, error) {
+	var accreditor Accreditor
+	identity, err := get_creator(stub)
+	if err != nil {
+		return accreditor, err
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("accreditor", []string{})
+	if err != nil {
+		return accreditor, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return accreditor, err
+		}
+		var candidate Accreditor
+		json.Unmarshal(record.Value, &candidate) //un stringify it aka JSON.parse()
+		if acts_for(identity, candidate.MspId, candidate.Identities) {
+			return candidate, nil
+		}
+	}
+	return accreditor, new_error(CodeUnauthorized, "Identity '"+identity.Id+"' does not act for any accreditor")
+}
+
+// ========================================================
+// Acts For - identity belongs to the msp and, when identities are listed, is one of them
+// ========================================================
+func acts_for(identity Identity, mspId string, identities []string) bool {
+	if identity.MspId != mspId {
+		return false
+	}
+	if len(identities) == 0 {
+		return true
+	}
+	for _, id := range identities {
+		if id == identity.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// ========================================================
+// Require Accreditor Of - creator must act for the accreditor of record of the university, or for the regulator
+// ========================================================
+func require_accreditor_of(stub shim.ChaincodeStubInterface, university_id string) (Accreditor, error) {
+	accreditor, err := get_creator_accreditor(stub)
+	if err != nil {
+		return accreditor, err
+	}
+	accreditation, err := get_accreditation(stub, university_id)
+	if err == nil && accreditation.AccreditorId == accreditor.Id {
+		return accreditor, nil
+	}
+
+	config, err := get_config(stub)
+	if err != nil {
+		return accreditor, err
+	}
+	if accreditor.MspId == config.RegulatorMsp {
+		return accreditor, nil
+	}
+	return accreditor, new_error(CodeUnauthorized, "Accreditor '"+accreditor.Id+"' is not the accreditor of record of university '"+university_id+"'")
+}
+
+// ========================================================
+// Get Accreditation - get the accreditation of a university from ledger
+// ========================================================
+func get_accreditation(stub shim.ChaincodeStubInterface, university_id string) (Accreditation, error) {
+	var accreditation Accreditation
+	accreditationKey, _ := stub.CreateCompositeKey("accreditation", []string{university_id})
+	accreditationAsBytes, err := stub.GetState(accreditationKey)
+	if err != nil {
+		return accreditation, errors.New("Failed to get accreditation - " + university_id)
+	}
+	if accreditationAsBytes == nil {
+		return accreditation, new_error(CodeNotFound, "University is not accredited - "+university_id)
+	}
+	err = json.Unmarshal(accreditationAsBytes, &accreditation) //un stringify it aka JSON.parse()
+	if err != nil {
+		return accreditation, errors.New("Accreditation is not readable - " + university_id)
+	}
+	return accreditation, nil
+}
+
+// ========================================================
+// Check Accreditation - fail unless the university holds an active accreditation valid at the given time
+// ========================================================
+func check_accreditation(stub shim.ChaincodeStubInterface, university_id string, at time.Time) error {
+	accreditation, err := get_accreditation(stub, university_id)
+	if err != nil {
+		return err
+	}
+	if accreditation.Status != "active" {
+		return new_error(CodeConflict, "Accreditation of university '"+university_id+"' is "+accreditation.Status)
+	}
+	validFrom, err := parse_day(accreditation.ValidFrom)
+	if err != nil {
+		return err
+	}
+	validUntil, err := parse_day(accreditation.ValidUntil)
+	if err != nil {
+		return err
+	}
+	if at.Before(validFrom) {
+		return new_error(CodeConflict, "Accreditation of university '"+university_id+"' is only valid from "+accreditation.ValidFrom)
+	}
+	if !at.Before(validUntil.AddDate(0, 0, 1)) {
+		return new_error(CodeConflict, "Accreditation of university '"+university_id+"' expired on "+accreditation.ValidUntil)
+	}
+	return nil
+}
+
+// ============================================================================================================================
+// Init Accreditor - register an accrediting body, admin only
+//
+// Inputs - JSON payload validated against accreditor_schema
+//
+//	{
+//		"id": "mec",
+//		"name": "Ministério da Educação",
+//		"mspId": "MECMSP",
+//		"identities": []
+//	}
+//
+// ============================================================================================================================
+func init_accreditor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting init_accreditor")
+
+	err = require_admin(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//input sanitation
+	var payload AccreditorPayload
+	err = parse_payload(args, accreditor_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check if accreditor already exists
+	_, err = get_accreditor(stub, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This accreditor already exists - "+payload.Id))
+	}
+
+	var accreditor Accreditor
+	accreditor.ObjectType = "accreditor"
+	accreditor.SchemaVersion = AccreditorSchemaVersion
+	accreditor.Id = payload.Id
+	accreditor.Name = payload.Name
+	accreditor.MspId = payload.MspId
+	accreditor.Identities = payload.Identities
+
+	//store accreditor
+	accreditorKey, _ := stub.CreateCompositeKey("accreditor", []string{accreditor.Id})
+	err = put_asset(stub, accreditorKey, &accreditor)
+	if err != nil {
+		fmt.Println("Could not store accreditor")
+		return error_response(err)
+	}
+
+	fmt.Println("- end init_accreditor")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Accredit University - grant or renew the accreditation of a university, accreditors only
+//
+// Any accreditor may grant a first accreditation. Only the accreditor of record or the regulator may renew one,
+// the accreditor of record stays the same. A suspension is lifted with set_accreditation_status and a revoked
+// accreditation is final.
+//
+// Inputs - JSON payload validated against accreditation_schema
+//
+//	{
+//		"university_id": "u123",
+//		"scope": ["bachelor", "master"],
+//		"act": "Portaria MEC 1.234/2017",
+//		"valid_from": "2017-01-01",
+//		"valid_until": "2021-12-31"
+//	}
+//
+// ============================================================================================================================
+func accredit_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting accredit_university")
+
+	accreditor, err := get_creator_accreditor(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//input sanitation
+	var payload AccreditationPayload
+	err = parse_payload(args, accreditation_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	field_errors := check_validity_days(payload.ValidFrom, payload.ValidUntil, "valid_from", "valid_until")
+	if len(payload.Scope) == 0 {
+		field_errors = append(field_errors, FieldError{Field: "scope", Message: "must list at least one kind of degree"})
+	}
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: accreditation_schema.Name, Fields: field_errors})
+	}
+
+	//check if university exists
+	_, err = get_university(stub, payload.UniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//a renewal is up to the accreditor of record or the regulator, and keeps the status it has
+	accreditation, err := get_accreditation(stub, payload.UniversityId)
+	if err == nil {
+		_, err = require_accreditor_of(stub, payload.UniversityId)
+		if err != nil {
+			return error_response(err)
+		}
+		if accreditation.Status == "revoked" {
+			return error_response(new_error(CodeConflict, "Accreditation of university '"+payload.UniversityId+"' is revoked for good"))
+		}
+		if accreditation.Status == "suspended" {
+			return error_response(new_error(CodeConflict, "Accreditation of university '"+payload.UniversityId+"' is suspended, lift it with set_accreditation_status first"))
+		}
+	} else if as_chaincode_error(err).Code != CodeNotFound {
+		return error_response(err)
+	} else {
+		accreditation.AccreditorId = accreditor.Id
+	}
+
+	accreditation.ObjectType = "accreditation"
+	accreditation.SchemaVersion = AccreditationSchemaVersion
+	accreditation.UniversityId = payload.UniversityId
+	accreditation.Status = "active"
+	accreditation.Scope = payload.Scope
+	accreditation.Act = payload.Act
+	accreditation.ValidFrom = payload.ValidFrom
+	accreditation.ValidUntil = payload.ValidUntil
+
+	err = put_accreditation(stub, accreditation)
+	if err != nil {
+		fmt.Println("Could not store accreditation")
+		return error_response(err)
+	}
+
+	fmt.Println("- end accredit_university")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Set Accreditation Status - suspend, revoke or reinstate an accreditation, accreditors only
+//
+// Inputs - Array of Strings
+//
+//	0             | 1                                  | 2
+//	university_id | status                             | reason
+//
+// "u123"         | "active"/"suspended"/"revoked"     | "Supervision process 123"
+// ============================================================================================================================
+func set_accreditation_status(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting set_accreditation_status")
+
+	if len(args) != 3 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+
+	university_id := args[0]
+	status := args[1]
+	reason := args[2]
+	if status != "active" && status != "suspended" && status != "revoked" {
+		return error_response(new_error(CodeInvalidArgument, "Status must be one of active, suspended or revoked - got '"+status+"'"))
+	}
+
+	// only the accreditor of record or the regulator may change it
+	_, err = require_accreditor_of(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	accreditation, err := get_accreditation(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+	if accreditation.Status == "revoked" {
+		return error_response(new_error(CodeConflict, "Accreditation of university '"+university_id+"' is revoked for good"))
+	}
+
+	accreditation.Status = status
+	accreditation.Reason = reason
+	err = put_accreditation(stub, accreditation)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end set_accreditation_status")
+	return shim.Success(nil)
+}
+
+// ========================================================
+// Put Accreditation - store the accreditation of a university
+// ========================================================
+func put_accreditation(stub shim.ChaincodeStubInterface, accreditation Accreditation) error {
+	accreditationKey, _ := stub.CreateCompositeKey("accreditation", []string{accreditation.UniversityId})
+	return put_asset(stub, accreditationKey, &accreditation)
+}
+
+// ============================================================================================================================
+// Read Accreditation - read the accreditation of a university and whether it is valid now
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_accreditation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type AccreditationStatus struct {
+		Accreditation Accreditation `json:"accreditation"`
+		Valid         bool          `json:"valid"`
+		Problem       string        `json:"problem,omitempty"`
+	}
+	var result AccreditationStatus
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	accreditation, err := get_accreditation(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	result.Accreditation = accreditation
+	err = check_accreditation(stub, args[0], now)
+	result.Valid = err == nil
+	if err != nil {
+		result.Problem = error_message(err)
+	}
+
+	fmt.Println("- end read_accreditation, valid " + strconv.FormatBool(result.Valid))
+	resultAsBytes, _ := json.Marshal(result) //convert to array of bytes
+	return shim.Success(resultAsBytes)
+}
@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+const test_renewal = `{"university_id": "u1", "scope": ["bachelor", "master"], "act": "Portaria 9", "valid_from": "2017-01-01", "valid_until": "2030-12-31"}`
+
+func TestAccreditUniversityRenewal(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string //status the accreditation of u1 is put in first, by MEC
+		mspId      string //accreditor renewing it
+		code       string
+		accreditor string //accreditor of record afterwards
+	}{
+		{"renewed by the accreditor of record", "", "MECMSP", "", "a1"},
+		{"renewed by another accreditor", "", "CEEMSP", CodeUnauthorized, "a1"},
+		{"suspended", "suspended", "MECMSP", CodeConflict, "a1"},
+		{"revoked", "revoked", "MECMSP", CodeConflict, "a1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := new_issuing_ledger(t)
+			ok(t, stub.as("Org1MSP", "admin").invoke("init_accreditor", `{"id": "a2", "name": "CEE", "mspId": "CEEMSP"}`))
+			if len(test.status) > 0 {
+				ok(t, stub.as("MECMSP", "inspector").invoke("set_accreditation_status", "u1", test.status, "Supervision 1"))
+			}
+			before, _ := get_accreditation(stub, "u1")
+
+			res := stub.as(test.mspId, "inspector").invoke("accredit_university", test_renewal)
+			after, _ := get_accreditation(stub, "u1")
+			if test.code == "" {
+				ok(t, res)
+				if after.Act != "Portaria 9" || after.Status != "active" {
+					t.Fatalf("accreditation was not renewed - %+v", after)
+				}
+			} else {
+				fails(t, res, test.code)
+				if after.Act != before.Act || after.Status != before.Status {
+					t.Fatalf("accreditation changed - %+v", after)
+				}
+			}
+			if after.AccreditorId != test.accreditor {
+				t.Fatalf("expected accreditor of record %s, got %s", test.accreditor, after.AccreditorId)
+			}
+		})
+	}
+}
+
+func TestRevokedAccreditationIsFinal(t *testing.T) {
+	stub := new_issuing_ledger(t).as("MECMSP", "inspector")
+	ok(t, stub.invoke("set_accreditation_status", "u1", "revoked", "Closed by the regulator"))
+	fails(t, stub.invoke("set_accreditation_status", "u1", "active", "Reopened"), CodeConflict)
+	fails(t, stub.invoke("accredit_university", test_renewal), CodeConflict)
+}
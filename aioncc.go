@@ -20,8 +20,8 @@ under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"strconv"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -34,26 +34,60 @@ type AionCertsChainCode struct {
 // Asset Definitions - The ledger will store certificates and institutions
 // ============================================================================================================================
 
-// ----- Marbles ----- //
+// ----- Certificate ----- //
 type Certificate struct {
-	ObjectType string `json:"docType"`                //field for couchdb
-	Id         string `json:"id"`                     //UUID of certificate
-	Name       string `json:"name"`                   //Name of the graduate
-	Document   string `json:"document"`               //Personal identification document (cpf)
-	Body       string `json:"body"`                   //Principal content of certificate
-	City       string `json:"city"`                   //Emission city
-	Date       string `json:"date"`                   //Emission timestamp
-	University UniversityRelation `json:"university"` //University
+	ObjectType    string             `json:"docType"`                //field for couchdb
+	SchemaVersion int                `json:"schemaVersion"`          //version of this record's layout
+	Id            string             `json:"id"`                     //UUID of certificate
+	Name          string             `json:"name"`                   //Name of the graduate
+	Document      string             `json:"document,omitempty"`     //Personal identification document, canonical form of its type, kept in the certificate_document record
+	DocumentType  string             `json:"documentType,omitempty"` //"cpf" or "passport", kept in the certificate_document record
+	Body          string             `json:"body"`                   //Principal content of certificate
+	City          string             `json:"city"`                   //Emission city
+	Date          string             `json:"date"`                   //Conferral date declared by the university, YYYY-MM-DD
+	University    UniversityRelation `json:"university"`             //University
+	CopyOf        string             `json:"copyOf"`                 //Id of the original certificate when this is a second copy
+	IssuedBy      string             `json:"issuedBy"`               //Id of the university that issued this record (custodian for copies)
+	DeanTerm      string             `json:"deanTerm"`               //Id of the dean term of the issuing university that signed it
+	SignedBy      string             `json:"signedBy"`               //identity that signed it
+	Delegation    string             `json:"delegation"`             //Id of the delegation the signer used, empty when the dean signed
+	UnitId        string             `json:"unitId"`                 //Id of the issuing campus, faculty or department
+	ProgramId     string             `json:"programId"`              //Id of the degree program of the issuing university
+	GraduateId    string             `json:"graduateId"`             //pseudonymous id of the graduate, HMAC of the CPF, set when an admin links it
+	IssuedAt      string             `json:"issuedAt"`               //transaction time the record was written, RFC 3339 UTC
+	Provenance                       //who created and last wrote it, filled by put_asset
 }
 
 // ----- University ----- //
 type University struct {
-	ObjectType     string `json:"docType"`        //field for couchdb
-	Id             string `json:"id"`             //UUID
-	Dean           string `json:"dean"`           //dean of university (reitor)
-	UniversityName string `json:"universityname"` //Name of university
-	Document       string `json:"document"`       //University national document (cnpj)
-	Certificates   []string `json:"certifcates"`  //Id of all certificates emitted
+	ObjectType     string   `json:"docType"`        //field for couchdb
+	SchemaVersion  int      `json:"schemaVersion"`  //version of this record's layout
+	Id             string   `json:"id"`             //UUID
+	Dean           string   `json:"dean"`           //dean of university (reitor)
+	UniversityName string   `json:"universityname"` //Name of university
+	Document       string   `json:"document"`       //University national document (cnpj)
+	Municipality   string   `json:"municipality"`   //municipality of the seat of the university, named on its diplomas
+	Certificates   []string `json:"certificates"`   //Id of all certificates emitted
+	Status         string   `json:"status"`         //"active", "suspended", "closed" or "merged"
+	StatusReason   string   `json:"statusReason"`   //reason of the last status change
+	StatusChanged  string   `json:"statusChanged"`  //RFC3339 transaction time of the last status change
+	Custodian      string   `json:"custodian"`      //Id of the university answering for the records once closed
+	Successor      string   `json:"successor"`      //Id of the university this one was merged into
+	Absorbed       []string `json:"absorbed"`       //Id of the universities merged into this one
+	CurrentTerm    string   `json:"currentTerm"`    //Id of the current dean term
+	PublicKey      string   `json:"publicKey"`      //PEM P-256 key exported credentials are signed with
+	Provenance              //who created and last wrote it, filled by put_asset
+}
+
+// ----- Certificate Document ----- //
+// the personal document of a certificate, restricted, never part of the public certificate record
+type CertificateDocument struct {
+	ObjectType    string `json:"docType"`       //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"` //version of this record's layout
+	CertificateId string `json:"certificateId"` //certificate the document belongs to
+	Document      string `json:"document"`      //Personal identification document, canonical form of its type
+	DocumentType  string `json:"documentType"`  //"cpf" or "passport"
+	Provenance           //who created and last wrote it, filled by put_asset
 }
 
 type UniversityRelation struct {
@@ -62,6 +96,227 @@ type UniversityRelation struct {
 	Name string `json:"name"` //cosmetic/handy, the real relation is by Id
 }
 
+// ----- Graduate ----- //
+type Graduate struct {
+	ObjectType    string   `json:"docType"`       //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"` //version of this record's layout
+	Id            string   `json:"id"`            //HMAC of the canonical CPF, hex encoded
+	Certificates  []string `json:"certificates"`  //Ids of every certificate of the graduate
+	Provenance             //who created and last wrote it, filled by put_asset
+}
+
+// ----- Transcript ----- //
+type Transcript struct {
+	ObjectType        string   `json:"docType"`           //field for couchdb
+	SchemaVersion     int      `json:"schemaVersion"`     //version of this record's layout
+	CertificateId     string   `json:"certificateId"`     //certificate the transcript belongs to, one transcript per certificate
+	CertificateDigest string   `json:"certificateDigest"` //sha256 of the certificate fields at issuance, hex encoded
+	UniversityId      string   `json:"universityId"`      //university that issued it
+	GraduateId        string   `json:"graduateId"`        //pseudonymous id of the graduate
+	Courses           []Course `json:"courses"`           //courses taken
+	TotalCreditHours  int      `json:"totalCreditHours"`  //credit hours of passed and exempt courses
+	Gpa               float64  `json:"gpa"`               //credit weighted average grade of passed and failed courses
+	DeanTerm          string   `json:"deanTerm"`          //Id of the dean term the transcript was signed under
+	SignedBy          string   `json:"signedBy"`          //identity that signed it
+	Delegation        string   `json:"delegation"`        //Id of the delegation the signer used, empty when the dean signed
+	Provenance                 //who created and last wrote it, filled by put_asset
+}
+
+type Course struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Term        string  `json:"term"`  //e.g. "2013/1"
+	Grade       float64 `json:"grade"` //0 to 10
+	CreditHours int     `json:"creditHours"`
+	Status      string  `json:"status"` //"passed", "failed" or "exempt"
+}
+
+// ----- Credit Recognition ----- //
+type CreditRecognition struct {
+	ObjectType            string             `json:"docType"`               //field for couchdb
+	SchemaVersion         int                `json:"schemaVersion"`         //version of this record's layout
+	Id                    string             `json:"id"`                    //UUID
+	CertificateId         string             `json:"certificateId"`         //certificate whose transcript holds the courses
+	GraduateId            string             `json:"graduateId"`            //pseudonymous id of the graduate
+	SourceUniversityId    string             `json:"sourceUniversityId"`    //university that issued the transcript
+	ReceivingUniversityId string             `json:"receivingUniversityId"` //university recognizing the credits
+	ReceivingProgramId    string             `json:"receivingProgramId"`    //program the credits count towards, optional
+	Items                 []RecognizedCourse `json:"items"`                 //equivalences
+	TotalCreditHours      int                `json:"totalCreditHours"`      //credit hours granted
+	Status                string             `json:"status"`                //"pending", "confirmed" or "rejected"
+	RequestedBy           string             `json:"requestedBy"`           //identity of the receiving university that recorded it
+	RequestedAt           string             `json:"requestedAt"`           //RFC3339 transaction time
+	DecidedBy             string             `json:"decidedBy"`             //identity of the source university that decided
+	DecidedAt             string             `json:"decidedAt"`             //RFC3339 transaction time
+	Reason                string             `json:"reason"`                //reason given with the decision
+	Provenance                               //who created and last wrote it, filled by put_asset
+}
+
+// ----- Credit Consent ----- //
+type CreditConsent struct {
+	ObjectType            string `json:"docType"`               //field for couchdb
+	SchemaVersion         int    `json:"schemaVersion"`         //version of this record's layout
+	CertificateId         string `json:"certificateId"`         //certificate whose transcript may be looked at
+	ReceivingUniversityId string `json:"receivingUniversityId"` //university allowed to recognize its credits
+	GrantedBy             string `json:"grantedBy"`             //identity the graduate granted it with
+	GrantedAt             string `json:"grantedAt"`             //RFC3339 transaction time
+	RecognitionId         string `json:"recognitionId"`         //recognition that used the grant, empty while unused
+	Provenance                   //who created and last wrote it, filled by put_asset
+}
+
+type RecognizedCourse struct {
+	SourceCode  string `json:"sourceCode"` //course code on the transcript
+	SourceTerm  string `json:"sourceTerm"` //term of the course on the transcript
+	TargetCode  string `json:"targetCode"` //equivalent course of the receiving university
+	TargetName  string `json:"targetName"`
+	CreditHours int    `json:"creditHours"` //credit hours granted
+}
+
+// ----- Program ----- //
+type Program struct {
+	ObjectType            string `json:"docType"`               //field for couchdb
+	SchemaVersion         int    `json:"schemaVersion"`         //version of this record's layout
+	Id                    string `json:"id"`                    //UUID
+	UniversityId          string `json:"universityId"`          //university offering the program
+	Name                  string `json:"name"`                  //Name of program
+	Level                 string `json:"level"`                 //kind of degree, e.g. "bachelor", "master"
+	FieldOfStudy          string `json:"fieldOfStudy"`          //Field of study
+	CreditHours           int    `json:"creditHours"`           //Total credit hours
+	EmecCode              string `json:"emecCode"`              //official e-MEC code
+	RecognitionAct        string `json:"recognitionAct"`        //official act (portaria) recognizing the program
+	RecognitionValidFrom  string `json:"recognitionValidFrom"`  //first valid day, YYYY-MM-DD
+	RecognitionValidUntil string `json:"recognitionValidUntil"` //last valid day, YYYY-MM-DD
+	Provenance                   //who created and last wrote it, filled by put_asset
+}
+
+// ----- Unit ----- //
+type Unit struct {
+	ObjectType    string    `json:"docType"`       //field for couchdb
+	SchemaVersion int       `json:"schemaVersion"` //version of this record's layout
+	Id            string    `json:"id"`            //UUID
+	UniversityId  string    `json:"universityId"`  //university the unit belongs to
+	ParentId      string    `json:"parentId"`      //unit this one belongs to, empty for top level units
+	Kind          string    `json:"kind"`          //"campus", "faculty" or "department"
+	Name          string    `json:"name"`          //Name of unit
+	Address       string    `json:"address"`       //Street address
+	City          string    `json:"city"`          //City of the unit
+	Officers      []Officer `json:"officers"`      //Responsible officers
+	Provenance              //who created and last wrote it, filled by put_asset
+}
+
+type Officer struct {
+	Role     string `json:"role"` //e.g. "director", "coordinator"
+	Name     string `json:"name"`
+	Identity string `json:"identity"` //"<msp>:<common name>", optional
+}
+
+// ----- Dean Term ----- //
+type DeanTerm struct {
+	ObjectType    string `json:"docType"`       //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"` //version of this record's layout
+	Id            string `json:"id"`            //Id of the term, the transaction that started it
+	UniversityId  string `json:"universityId"`  //university the dean runs
+	Name          string `json:"name"`          //name of the dean
+	Identity      string `json:"identity"`      //"<msp>:<common name>" the dean signs with
+	StartDate     string `json:"startDate"`     //RFC3339Nano transaction time the term started
+	EndDate       string `json:"endDate"`       //RFC3339Nano transaction time the term ended, empty while current
+	Provenance           //who created and last wrote it, filled by put_asset
+}
+
+// ----- Delegation ----- //
+type Delegation struct {
+	ObjectType    string   `json:"docType"`       //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"` //version of this record's layout
+	Id            string   `json:"id"`            //UUID
+	UniversityId  string   `json:"universityId"`  //university the registrar issues for
+	DeanTerm      string   `json:"deanTerm"`      //Id of the granting dean term, the delegation lapses with it
+	Registrar     string   `json:"registrar"`     //"<msp>:<common name>" of the registrar
+	Programs      []string `json:"programs"`      //programs covered, empty for all
+	Campuses      []string `json:"campuses"`      //campuses covered, empty for all
+	ValidFrom     string   `json:"validFrom"`     //first valid day, YYYY-MM-DD
+	ValidUntil    string   `json:"validUntil"`    //last valid day, YYYY-MM-DD
+	Revoked       bool     `json:"revoked"`
+	RevokedAt     string   `json:"revokedAt"` //RFC3339Nano transaction time of the revocation
+	RevokedReason string   `json:"revokedReason"`
+	Provenance             //who created and last wrote it, filled by put_asset
+}
+
+// ----- Accreditor ----- //
+type Accreditor struct {
+	ObjectType    string   `json:"docType"`       //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"` //version of this record's layout
+	Id            string   `json:"id"`            //UUID
+	Name          string   `json:"name"`          //Name of the accrediting body (e.g. MEC)
+	MspId         string   `json:"mspId"`         //MSP whose members act for the accreditor
+	Identities    []string `json:"identities"`    //if not empty, only these "<msp>:<common name>" identities act for it
+	Provenance             //who created and last wrote it, filled by put_asset
+}
+
+// ----- Accreditation ----- //
+type Accreditation struct {
+	ObjectType    string   `json:"docType"`       //field for couchdb
+	SchemaVersion int      `json:"schemaVersion"` //version of this record's layout
+	UniversityId  string   `json:"universityId"`  //accredited university, one accreditation per university
+	AccreditorId  string   `json:"accreditorId"`  //accreditor that granted it
+	Status        string   `json:"status"`        //"active", "suspended" or "revoked"
+	Reason        string   `json:"reason"`        //reason of the last status change
+	Scope         []string `json:"scope"`         //kinds of degrees the university may confer
+	Act           string   `json:"act"`           //official act (portaria) of the accreditation
+	ValidFrom     string   `json:"validFrom"`     //first valid day, YYYY-MM-DD
+	ValidUntil    string   `json:"validUntil"`    //last valid day, YYYY-MM-DD
+	Provenance             //who created and last wrote it, filled by put_asset
+}
+
+// ----- Proposal ----- //
+type Proposal struct {
+	ObjectType    string          `json:"docType"`       //field for couchdb
+	SchemaVersion int             `json:"schemaVersion"` //version of this record's layout
+	Id            string          `json:"id"`            //UUID
+	Action        string          `json:"action"`        //"admit_university", "suspend_university" or "change_config"
+	Payload       json.RawMessage `json:"payload"`       //payload of the action
+	Proposer      string          `json:"proposer"`      //identity that opened it
+	Status        string          `json:"status"`        //"open", "executed", "rejected" or "expired"
+	Members       []string        `json:"members"`       //member MSPs allowed to vote, fixed at opening
+	QuorumPercent int             `json:"quorumPercent"` //share of members that must approve, fixed at opening
+	CreatedAt     string          `json:"createdAt"`     //RFC3339 transaction time of opening
+	Deadline      string          `json:"deadline"`      //RFC3339 end of voting
+	Votes         []Vote          `json:"votes"`         //votes cast so far
+	ExecutedTxId  string          `json:"executedTxId"`  //transaction that executed the action
+	Provenance                    //who created and last wrote it, filled by put_asset
+}
+
+type Vote struct {
+	Member   string `json:"member"`   //MSP of the voting member
+	Identity string `json:"identity"` //identity that cast the vote
+	Approve  bool   `json:"approve"`
+	TxId     string `json:"txId"`
+}
+
+// ----- Provenance ----- //
+// embedded in every asset
+type Provenance struct {
+	CreatedBy *WriteStamp `json:"createdBy,omitempty"` //write that created the asset, empty for assets created before provenance was recorded
+	UpdatedBy *WriteStamp `json:"updatedBy,omitempty"` //latest write
+}
+
+type WriteStamp struct {
+	Identity    string `json:"identity"`    //"<msp>:<common name>" of the submitter
+	MspId       string `json:"mspId"`       //MSP of the submitter
+	Fingerprint string `json:"fingerprint"` //sha256 of the submitter's DER certificate, hex encoded
+	TxId        string `json:"txId"`        //transaction of the write
+	Timestamp   string `json:"timestamp"`   //RFC3339 transaction time
+	ChannelId   string `json:"channelId"`   //channel of the transaction
+}
+
+// ----- Identity ----- //
+type Identity struct {
+	Id          string   `json:"id"`          //"<msp>:<common name>", how identities are listed on the ledger
+	MspId       string   `json:"mspId"`       //MSP of the submitting organization
+	CommonName  string   `json:"commonName"`  //CN of the x509 certificate
+	OrgUnits    []string `json:"orgUnits"`    //OUs of the x509 certificate
+	Fingerprint string   `json:"fingerprint"` //sha256 of the DER certificate, hex encoded
+}
+
 // ============================================================================================================================
 // Main
 // ============================================================================================================================
@@ -73,69 +328,71 @@ func main() {
 }
 
 // ============================================================================================================================
-// Init - initialize the chaincode - runs a simple test
+// Init - store the chaincode config on instantiate, leave existing state alone on upgrade
+//
+// Inputs - JSON payload validated against config_schema (see update_config)
 // ============================================================================================================================
 func (t *AionCertsChainCode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	fmt.Println("AionCerts Is Starting Up")
 	_, args := stub.GetFunctionAndParameters()
-	var Aval int
-	var err error
 
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+	// an existing config means this is an upgrade, the ledger is left untouched
+	configAsBytes, err := stub.GetState(config_key(stub))
+	if err != nil {
+		return error_response(wrap_error("Failed to get chaincode config", err))
+	}
+	if configAsBytes != nil {
+		if len(args) > 0 {
+			fmt.Println(" - ignoring Init arguments on upgrade, use update_config to change the config")
+		}
+		fmt.Println(" - found existing config, ready for action")
+		return shim.Success(nil)
 	}
 
-	// convert numeric string to integer
-	Aval, err = strconv.Atoi(args[0])
+	config, err := parse_config(stub, args)
 	if err != nil {
-		return shim.Error("Expecting a numeric string argument to Init()")
+		return error_response(wrap_error("Init expects the chaincode config", err))
 	}
-
-	// this is a very simple dumb test.  let's write to the ledger and error on any errors
-	err = stub.PutState("selftest", []byte(strconv.Itoa(Aval))) //making a test var "selftest", its handy to read this right away to test the network
+	err = put_config(stub, config)
 	if err != nil {
-		return shim.Error(err.Error()) //self-test fail
+		return error_response(err)
 	}
 
-	fmt.Println(" - ready for action") //self-test pass
+	fmt.Println(" - ready for action")
 	return shim.Success(nil)
 }
 
 // ============================================================================================================================
 // Invoke - Our entry point for Invocations
+//
+// Functions are dispatched through the handler registry, which checks their arguments and the roles of the caller
+// first, see registry.go or call describe for the catalog.
+// Every failing function returns a JSON ChaincodeError in its shim.Error message, {"code", "message", "fields"},
+// see errors.go for the codes.
+//
+// Breaking change - the generic write function, which let any caller overwrite any key, was removed with the registry.
+// Clients write through the typed functions (init_university, init_cert, ...), calling write now fails as an unknown
+// function with INVALID_ARGUMENT.
 // ============================================================================================================================
 func (t *AionCertsChainCode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	function, args := stub.GetFunctionAndParameters()
 	fmt.Println(" ")
 	fmt.Println("starting invoke, for - " + function)
 
-	// Handle different functions
-	if function == "init" { //initialize the chaincode state, used as reset
-		return t.Init(stub)
-	} else if function == "read" { //generic read ledger
-		return read(stub, args)
-	} else if function == "write" { //generic writes to ledger
-		return write(stub, args)
-	} else if function == "init_cert" { //create a new certificate
-		return init_cert(stub, args)
-	} else if function == "set_dean" { //change owner of a marble
-		return set_dean(stub, args)
-	} else if function == "init_university" { //create a new marble owner
-		return init_university(stub, args)
-	} else if function == "read_everything" { //read all certificates from university
-		return read_all_certificates_from_university(stub, args)
-	} else if function == "getUniversityHistory" { //read history of a university
-		return getHistory(stub, args)
+	// Handle registered functions
+	handler, found := find_handler(function)
+	if found {
+		return call_handler(stub, handler, args)
 	}
 
 	// error out
 	fmt.Println("Received unknown invoke function name - " + function)
-	return shim.Error("Received unknown invoke function name - '" + function + "'")
+	return error_response(new_error(CodeInvalidArgument, "Received unknown invoke function name - '"+function+"'"))
 }
 
 // ============================================================================================================================
 // Query - legacy function
 // ============================================================================================================================
 func (t *AionCertsChainCode) Query(stub shim.ChaincodeStubInterface) pb.Response {
-	return shim.Error("Unknown supported call - Query()")
+	return error_response(new_error(CodeInvalidArgument, "Unknown supported call - Query()"))
 }
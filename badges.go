@@ -0,0 +1,220 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jsilvaigor/AionChainCode/openbadges"
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+// ========================================================
+// Build Badge Credential - the unsigned Open Badges credential of a non-degree certificate and the university that signs it
+//
+// The achievement comes from the program catalog.
+// ========================================================
+func build_badge_credential(stub shim.ChaincodeStubInterface, certificate Certificate) (vc.Credential, University, error) {
+	var credential vc.Credential
+	if len(certificate.ProgramId) == 0 {
+		return credential, University{}, new_error(CodeConflict, "Certificate '"+certificate.Id+"' has no program to take the achievement from")
+	}
+	program, err := get_program(stub, certificate.University.Id, certificate.ProgramId)
+	if err != nil {
+		return credential, University{}, err
+	}
+	if !non_degree_levels[program.Level] {
+		return credential, University{}, new_error(CodeConflict, "Program '"+program.Id+"' is a "+program.Level+" degree, export it with export_vc")
+	}
+	issuer, err := get_university(stub, certificate.IssuedBy)
+	if err != nil {
+		return credential, issuer, err
+	}
+	issuedAt, err := certificate_issued_at(stub, certificate)
+	if err != nil {
+		return credential, issuer, err
+	}
+
+	var subject openbadges.Subject
+	if len(certificate.GraduateId) > 0 {
+		subject.Id = ledger_uri(stub, "graduate", certificate.GraduateId)
+	}
+	subject.Name = certificate.Name
+	subject.Achievement = openbadges.Achievement{
+		Id:              ledger_uri(stub, "program", certificate.University.Id+"/"+program.Id),
+		Name:            program.Name,
+		Description:     certificate.Body,
+		Criteria:        "Completed the " + strconv.Itoa(program.CreditHours) + " hour " + program.Level + " " + program.Name,
+		AchievementType: openbadges.AchievementType(program.Level),
+		FieldOfStudy:    program.FieldOfStudy,
+		CreditHours:     program.CreditHours,
+	}
+
+	credential = openbadges.New(
+		ledger_uri(stub, "certificate", certificate.Id),
+		vc.Issuer{Id: ledger_uri(stub, "university", issuer.Id), Name: issuer.UniversityName},
+		issuedAt,
+		subject,
+	)
+	credential.CredentialStatus = certificate_status(stub, certificate.Id)
+	return credential, issuer, nil
+}
+
+// ============================================================================================================================
+// Export Open Badge - a non-degree certificate as an Open Badges 3.0 credential, with the graduate portfolio access rules
+//
+// Returned unsigned, like export_vc.
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// Returns - the unsigned Open Badges credential JSON-LD document
+// ============================================================================================================================
+func export_openbadge(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_graduate_reader(stub, Graduate{Id: certificate.GraduateId}, []Certificate{certificate})
+	if err != nil {
+		return error_response(err)
+	}
+
+	credential, _, err := build_badge_credential(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+
+	credentialAsBytes, _ := json.Marshal(credential) //convert to array of bytes
+	return shim.Success(credentialAsBytes)
+}
+
+// ============================================================================================================================
+// Check Open Badge - validate an imported Open Badges credential against the ledger
+//
+// The badge must name a certificate of this channel, carry exactly the achievement the ledger would export,
+// and be signed with the registered key of the issuing university. Signed badges are VC-JWTs made with vc.Sign,
+// an unsigned credential JSON is checked too and reported as unsigned.
+//
+// Inputs - Array of strings
+//
+//	0
+//	badge (VC-JWT or credential JSON)
+//	"eyJhbGciOiJFUzI1NiIsInR5cCI6IkpXVCJ9..."
+//
+// Returns - { "certificateId": "c123", "valid": true, "problems": [] }
+// ============================================================================================================================
+func check_openbadge(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Check struct {
+		CertificateId string   `json:"certificateId"`
+		Valid         bool     `json:"valid"`
+		Problems      []string `json:"problems"`
+	}
+	var check Check
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	var credential vc.Credential
+	var subject openbadges.Subject
+	var err error
+	signed := !strings.HasPrefix(strings.TrimSpace(args[0]), "{")
+	if signed {
+		credential, err = vc.Decode(args[0])
+		if err == nil {
+			subject, err = openbadges.Validate(credential)
+		}
+	} else {
+		credential, subject, err = openbadges.Parse([]byte(args[0]))
+	}
+	if err != nil {
+		return error_response(new_error(CodeInvalidArgument, err.Error()))
+	}
+	prefix := ledger_uri(stub, "certificate", "")
+	if !strings.HasPrefix(credential.Id, prefix) {
+		return error_response(new_error(CodeInvalidArgument, "Badge '"+credential.Id+"' was not issued on this channel"))
+	}
+	check.CertificateId = strings.TrimPrefix(credential.Id, prefix)
+
+	certificate, err := get_certificate(stub, check.CertificateId)
+	if err != nil {
+		return error_response(err)
+	}
+	expected, issuer, err := build_badge_credential(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+	expectedSubject, _ := openbadges.Validate(expected)
+
+	//the badge must say what the ledger says
+	if credential.Issuer.Id != expected.Issuer.Id {
+		check.Problems = append(check.Problems, "Issuer is '"+credential.Issuer.Id+"', the ledger has '"+expected.Issuer.Id+"'")
+	}
+	if credential.IssuanceDate != expected.IssuanceDate {
+		check.Problems = append(check.Problems, "Issuance date is '"+credential.IssuanceDate+"', the ledger has '"+expected.IssuanceDate+"'")
+	}
+	if subject != expectedSubject {
+		check.Problems = append(check.Problems, "Recipient or achievement differ from the ledger")
+	}
+
+	//signed with the key the university registered
+	if !signed {
+		check.Problems = append(check.Problems, "Badge is not signed, expecting a VC-JWT")
+	} else if len(issuer.PublicKey) == 0 {
+		check.Problems = append(check.Problems, "University '"+issuer.Id+"' has no registered key")
+	} else {
+		publicKey, err := vc.ParsePublicKey([]byte(issuer.PublicKey))
+		if err == nil {
+			_, err = vc.Verify(args[0], publicKey)
+		}
+		if err != nil {
+			check.Problems = append(check.Problems, error_message(err))
+		}
+	}
+
+	//and the certificate behind it must hold
+	if len(certificate.DeanTerm) > 0 {
+		issuedAt, err := certificate_issued_at(stub, certificate)
+		if err == nil {
+			err = check_signature_at(stub, certificate, issuedAt)
+		}
+		if err != nil {
+			check.Problems = append(check.Problems, error_message(err))
+		}
+	}
+
+	check.Valid = len(check.Problems) == 0
+	checkAsBytes, _ := json.Marshal(check) //convert to array of bytes
+	return shim.Success(checkAsBytes)
+}
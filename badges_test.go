@@ -0,0 +1,96 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+func TestExportAndCheckOpenBadge(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("Org1MSP", "joao").invoke("init_program", `{"id": "w1", "university_id": "u1", "name": "Git", "level": "workshop", "field_of_study": "Computing", "credit_hours": 8}`))
+	ok(t, stub.issue(map[string]interface{}{"id": "c1", "program_id": "w1", "body": "Git workshop"}))
+	ok(t, stub.issue(map[string]interface{}{"id": "c2"}))
+
+	fails(t, stub.as("Org1MSP", "joao").invoke("export_openbadge", "c2"), CodeConflict)
+	fails(t, stub.as("Org9MSP", "mallory").invoke("export_openbadge", "c1"), CodeUnauthorized)
+	exported := ok(t, stub.as("Org9MSP", "maria").with(map[string]string{"cpf": test_cpf}).invoke("export_openbadge", "c1"))
+
+	//the university signs off-chain with the key it registered
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	publicKeyAsBytes, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	sign := func(change func(c *vc.Credential), with *ecdsa.PrivateKey) string {
+		credential, err := vc.Parse(exported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		change(&credential)
+		token, err := vc.Sign(credential, with, credential.Issuer.Id+"#key-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	untouched := func(c *vc.Credential) {}
+	signed := sign(untouched, key)
+
+	type Check struct {
+		CertificateId string   `json:"certificateId"`
+		Valid         bool     `json:"valid"`
+		Problems      []string `json:"problems"`
+	}
+	var check Check
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("check_openbadge", signed)), &check)
+	if check.Valid || len(check.Problems) != 1 {
+		t.Fatalf("without a registered key - %+v", check)
+	}
+	ok(t, stub.as("Org1MSP", "joao").invoke("register_university_key", "u1", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyAsBytes}))))
+
+	tests := []struct {
+		name  string
+		badge string
+		valid bool
+	}{
+		{"signed by the university", signed, true},
+		{"unsigned", string(exported), false},
+		{"signed with another key", sign(untouched, other), false},
+		{"other recipient", sign(func(c *vc.Credential) { c.CredentialSubject["name"] = "Mario" }, key), false},
+		{"other issuance date", sign(func(c *vc.Credential) { c.IssuanceDate = "2016-01-01T00:00:00Z" }, key), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var check Check
+			json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("check_openbadge", test.badge)), &check)
+			if check.CertificateId != "c1" || check.Valid != test.valid {
+				t.Fatalf("check %+v", check)
+			}
+		})
+	}
+	fails(t, stub.as("Org9MSP", "anyone").invoke("check_openbadge", `{"@context": []}`), CodeInvalidArgument)
+}
@@ -0,0 +1,249 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Chaincode Config ----- //
+type ChaincodeConfig struct {
+	ObjectType        string          `json:"docType"`           //field for couchdb
+	SchemaVersion     int             `json:"schemaVersion"`     //version of this record's layout
+	RegulatorMsp      string          `json:"regulatorMsp"`      //MSP of the regulator (MEC)
+	Admins            []string        `json:"admins"`            //identities allowed to administer the chaincode, "<msp>:<common name>"
+	Features          map[string]bool `json:"features"`          //feature options
+	Governance        GovernanceRules `json:"governance"`        //consortium voting rules
+	GraduateKeyDigest string          `json:"graduateKeyDigest"` //sha256 of the key graduate ids are derived with, hex encoded
+	DatePolicy        DatePolicy      `json:"datePolicy"`        //how far certificate dates may be from their issuance
+	UpdatedTxId       string          `json:"updatedTxId"`       //transaction that last wrote the config
+	Provenance                        //who created and last wrote it, filled by put_asset
+}
+
+// ----- Governance Rules ----- //
+type GovernanceRules struct {
+	Members           []string `json:"members"`           //MSPs of the consortium members, one vote each
+	QuorumPercent     int      `json:"quorumPercent"`     //share of members that must approve, 1-100
+	VotingPeriodHours int      `json:"votingPeriodHours"` //how long a proposal stays open
+}
+
+// ----- Config Payload ----- //
+type ConfigPayload struct {
+	RegulatorMsp      string          `json:"regulatorMsp"`
+	Admins            []string        `json:"admins"`
+	Features          map[string]bool `json:"features"`
+	Governance        GovernanceRules `json:"governance"`
+	GraduateKeyDigest string          `json:"graduateKeyDigest"`
+	DatePolicy        DatePolicy      `json:"datePolicy"`
+}
+
+var config_schema = PayloadSchema{
+	Name: "config",
+	Fields: []FieldSpec{
+		{Name: "regulatorMsp", Type: "string", Required: true, MaxLength: 64, Description: "MSP of the regulator (MEC)"},
+		{Name: "admins", Type: "array", Required: true, Description: "Admin identities, \"<msp>:<common name>\""},
+		{Name: "features", Type: "object", Description: "Feature options, name to enabled, \"governance\" requires proposals to admit universities and change the config"},
+		{Name: "governance", Type: "object", Description: "Consortium voting rules, {members, quorumPercent, votingPeriodHours}"},
+		{Name: "graduateKeyDigest", Type: "string", MaxLength: 64, Description: "sha256 of the graduate key, hex encoded, the key itself stays off the ledger"},
+		{Name: "datePolicy", Type: "object", Description: "Allowed distance of certificate dates from issuance in days, {maxBackdateDays, maxFutureDays}, 0 means no limit on that side"},
+	},
+}
+
+// ========================================================
+// Config Key - the config asset lives under a composite key so range scans over assets never see it
+// ========================================================
+func config_key(stub shim.ChaincodeStubInterface) string {
+	configKey, _ := stub.CreateCompositeKey("config", []string{"chaincode"})
+	return configKey
+}
+
+// ========================================================
+// Get Config - get the chaincode config asset from ledger
+// ========================================================
+func get_config(stub shim.ChaincodeStubInterface) (ChaincodeConfig, error) {
+	var config ChaincodeConfig
+	configAsBytes, err := stub.GetState(config_key(stub))
+	if err != nil {
+		return config, errors.New("Failed to get chaincode config - " + err.Error())
+	}
+	if configAsBytes == nil {
+		return config, new_error(CodeNotFound, "Chaincode config does not exist, instantiate the chaincode with a config")
+	}
+	err = json.Unmarshal(configAsBytes, &config) //un stringify it aka JSON.parse()
+	if err != nil {
+		return config, errors.New("Chaincode config is not readable - " + err.Error())
+	}
+	return config, nil
+}
+
+// ========================================================
+// Parse Config - validate a config payload and build the config asset from it
+// ========================================================
+func parse_config(stub shim.ChaincodeStubInterface, args []string) (ChaincodeConfig, error) {
+	var config ChaincodeConfig
+	var payload ConfigPayload
+
+	err := parse_payload(args, config_schema, &payload)
+	if err != nil {
+		return config, err
+	}
+	var field_errors []FieldError
+	if len(payload.Admins) == 0 {
+		field_errors = append(field_errors, FieldError{Field: "admins", Message: "must list at least one admin identity"})
+	}
+	for i, admin := range payload.Admins {
+		if err := check_identity_format(admin); err != nil {
+			field_errors = append(field_errors, FieldError{Field: "admins[" + strconv.Itoa(i) + "]", Message: error_message(err)})
+		}
+	}
+	if len(payload.Governance.Members) > 0 || payload.Features["governance"] {
+		if len(payload.Governance.Members) == 0 {
+			field_errors = append(field_errors, FieldError{Field: "governance.members", Message: "must list at least one member MSP"})
+		}
+		if payload.Governance.QuorumPercent < 1 || payload.Governance.QuorumPercent > 100 {
+			field_errors = append(field_errors, FieldError{Field: "governance.quorumPercent", Message: "must be between 1 and 100"})
+		}
+		if payload.Governance.VotingPeriodHours < 1 {
+			field_errors = append(field_errors, FieldError{Field: "governance.votingPeriodHours", Message: "must be at least 1"})
+		}
+	}
+	if len(payload.GraduateKeyDigest) > 0 {
+		if _, err := hex.DecodeString(payload.GraduateKeyDigest); err != nil || len(payload.GraduateKeyDigest) != 64 {
+			field_errors = append(field_errors, FieldError{Field: "graduateKeyDigest", Message: "must be a hex encoded sha256 digest"})
+		}
+	}
+	if payload.DatePolicy.MaxBackdateDays < 0 {
+		field_errors = append(field_errors, FieldError{Field: "datePolicy.maxBackdateDays", Message: "must be 0, no limit, or more"})
+	}
+	if payload.DatePolicy.MaxFutureDays < 0 {
+		field_errors = append(field_errors, FieldError{Field: "datePolicy.maxFutureDays", Message: "must be 0, no limit, or more"})
+	}
+	if len(field_errors) > 0 {
+		return config, ValidationError{Schema: config_schema.Name, Fields: field_errors}
+	}
+
+	config.ObjectType = "config"
+	config.SchemaVersion = ConfigSchemaVersion
+	config.RegulatorMsp = payload.RegulatorMsp
+	config.Admins = payload.Admins
+	config.Features = payload.Features
+	if config.Features == nil {
+		config.Features = make(map[string]bool)
+	}
+	config.Governance = payload.Governance
+	config.GraduateKeyDigest = strings.ToLower(payload.GraduateKeyDigest)
+	config.DatePolicy = payload.DatePolicy
+	config.UpdatedTxId = stub.GetTxID()
+	return config, nil
+}
+
+// ========================================================
+// Put Config - store the config asset
+// ========================================================
+func put_config(stub shim.ChaincodeStubInterface, config ChaincodeConfig) error {
+	return put_asset(stub, config_key(stub), &config)
+}
+
+// ========================================================
+// Require Admin - fail unless the transaction creator is listed as a chaincode admin
+// ========================================================
+func require_admin(stub shim.ChaincodeStubInterface) error {
+	identity, err := get_creator(stub)
+	if err != nil {
+		return err
+	}
+	config, err := get_config(stub)
+	if err != nil {
+		return err
+	}
+	for _, admin := range config.Admins {
+		if admin == identity.Id {
+			return nil
+		}
+	}
+	return new_error(CodeUnauthorized, "Identity '"+identity.Id+"' is not a chaincode admin")
+}
+
+// ============================================================================================================================
+// Update Config - replace the chaincode config, admin only
+//
+// Once the governance feature is on the config belongs to the consortium, it changes through a change_config proposal
+// and update_config is refused.
+//
+// Inputs - JSON payload validated against config_schema
+//
+//	{
+//		"regulatorMsp": "MECMSP",
+//		"admins": ["Org1MSP:Admin@org1.example.com"],
+//		"features": {"governance": true},
+//		"governance": {"members": ["Org1MSP", "Org2MSP", "Org3MSP"], "quorumPercent": 66, "votingPeriodHours": 72},
+//		"graduateKeyDigest": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//		"datePolicy": {"maxBackdateDays": 3650, "maxFutureDays": 1}
+//	}
+//
+// ============================================================================================================================
+func update_config(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("starting update_config")
+
+	err := require_admin(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	current, err := get_config(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	if current.Features["governance"] {
+		return error_response(new_error(CodeConflict, "The config is changed by consortium vote, submit a change_config proposal instead"))
+	}
+
+	config, err := parse_config(stub, args)
+	if err != nil {
+		return error_response(err)
+	}
+	err = put_config(stub, config)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end update_config")
+	configAsBytes, _ := json.Marshal(config) //convert to array of bytes
+	return shim.Success(configAsBytes)
+}
+
+// ============================================================================================================================
+// Read Config - return the chaincode config
+// ============================================================================================================================
+func read_config(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	config, err := get_config(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	configAsBytes, _ := json.Marshal(config) //convert to array of bytes
+	return shim.Success(configAsBytes)
+}
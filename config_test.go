@@ -0,0 +1,87 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInitValidatesTheConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		field  string //offending field, "" when Init succeeds
+	}{
+		{"valid", `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin"]}`, ""},
+		{"no admins", `{"regulatorMsp": "MECMSP", "admins": []}`, "admins"},
+		{"admin without msp", `{"regulatorMsp": "MECMSP", "admins": ["admin"]}`, "admins[0]"},
+		{"governance without members", `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin"], "features": {"governance": true}, "governance": {"quorumPercent": 50, "votingPeriodHours": 24}}`, "governance.members"},
+		{"quorum over 100", `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin"], "governance": {"members": ["Org1MSP"], "quorumPercent": 101, "votingPeriodHours": 24}}`, "governance.quorumPercent"},
+		{"graduate key digest not sha256", `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin"], "graduateKeyDigest": "abc"}`, "graduateKeyDigest"},
+		{"negative future days", `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin"], "datePolicy": {"maxFutureDays": -1}}`, "datePolicy.maxFutureDays"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := new_test_stub()
+			res := stub.as("Org1MSP", "admin").init(test.config)
+			if test.field == "" {
+				ok(t, res)
+				return
+			}
+			chaincodeError := fails(t, res, CodeInvalidArgument)
+			if len(chaincodeError.Fields) != 1 || chaincodeError.Fields[0].Field != test.field {
+				t.Fatalf("fields %+v", chaincodeError.Fields)
+			}
+			if configAsBytes, _ := stub.GetState(config_key(stub)); configAsBytes != nil {
+				t.Fatal("an invalid config was stored")
+			}
+		})
+	}
+}
+
+func TestUpgradeKeepsTheConfig(t *testing.T) {
+	stub := new_test_ledger()
+	before, _ := stub.GetState(config_key(stub))
+
+	//an upgrade runs Init again, with or without arguments
+	ok(t, stub.init())
+	ok(t, stub.init(`{"regulatorMsp": "OtherMSP", "admins": ["Org9MSP:mallory"]}`))
+	after, _ := stub.GetState(config_key(stub))
+	if string(before) != string(after) {
+		t.Fatalf("upgrade changed the config - %s", after)
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	stub := new_test_ledger()
+	update := `{"regulatorMsp": "MECMSP", "admins": ["Org1MSP:admin", "Org2MSP:admin"], "datePolicy": {"maxFutureDays": 30}}`
+
+	fails(t, stub.as("Org9MSP", "mallory").invoke("update_config", update), CodeUnauthorized)
+	fails(t, stub.as("Org1MSP", "admin").invoke("update_config", `{"regulatorMsp": "MECMSP", "admins": []}`), CodeInvalidArgument)
+	ok(t, stub.as("Org1MSP", "admin").invoke("update_config", update))
+
+	var config ChaincodeConfig
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("read_config")), &config)
+	if len(config.Admins) != 2 || config.DatePolicy.MaxFutureDays != 30 || config.SchemaVersion != ConfigSchemaVersion {
+		t.Fatalf("config %+v", config)
+	}
+	ok(t, stub.as("Org2MSP", "admin").invoke("update_config", update))
+}
@@ -0,0 +1,177 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+// ========================================================
+// Ledger URI - identifies a record of this channel in exported documents, "urn:aion:<channel>:<kind>:<id>"
+// ========================================================
+func ledger_uri(stub shim.ChaincodeStubInterface, kind string, id string) string {
+	return "urn:aion:" + stub.GetChannelID() + ":" + kind + ":" + id
+}
+
+// ========================================================
+// Build Certificate Credential - the unsigned verifiable credential of a certificate
+// ========================================================
+func build_certificate_credential(stub shim.ChaincodeStubInterface, certificate Certificate) (vc.Credential, error) {
+	var credential vc.Credential
+	issuer, err := get_university(stub, certificate.IssuedBy)
+	if err != nil {
+		return credential, err
+	}
+	issuedAt, err := certificate_issued_at(stub, certificate)
+	if err != nil {
+		return credential, err
+	}
+
+	subject := map[string]interface{}{
+		"name":        certificate.Name,
+		"description": certificate.Body,
+	}
+	if len(certificate.GraduateId) > 0 {
+		subject["id"] = ledger_uri(stub, "graduate", certificate.GraduateId)
+	}
+	if len(certificate.ProgramId) > 0 {
+		program, err := get_program(stub, certificate.University.Id, certificate.ProgramId)
+		if err != nil {
+			return credential, err
+		}
+		subject["degree"] = map[string]interface{}{
+			"type":         program.Level,
+			"name":         program.Name,
+			"fieldOfStudy": program.FieldOfStudy,
+			"emecCode":     program.EmecCode,
+		}
+	}
+
+	credential = vc.New(
+		ledger_uri(stub, "certificate", certificate.Id),
+		[]string{"UniversityDegreeCredential"},
+		vc.Issuer{Id: ledger_uri(stub, "university", issuer.Id), Name: issuer.UniversityName},
+		issuedAt,
+		subject,
+	)
+	credential.CredentialStatus = certificate_status(stub, certificate.Id)
+	return credential, nil
+}
+
+// ========================================================
+// Certificate Status - the credentialStatus entry of an exported certificate, answered by verify_certificate
+// ========================================================
+func certificate_status(stub shim.ChaincodeStubInterface, certificate_id string) *vc.Status {
+	return &vc.Status{Id: ledger_uri(stub, "certificate", certificate_id) + "#status", Type: "AionLedgerStatus"}
+}
+
+// ============================================================================================================================
+// Register University Key - set the P-256 public key the university signs its exported credentials with off-chain, dean or accreditor of record
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | public_key (PEM)
+//
+// "u123"         | "-----BEGIN PUBLIC KEY-----\n..."
+// ============================================================================================================================
+func register_university_key(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting register_university_key")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = vc.ParsePublicKey([]byte(args[1]))
+	if err != nil {
+		return error_response(new_error(CodeInvalidArgument, err.Error()))
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_signer(stub, university)
+	if err != nil {
+		_, err = require_accreditor_of(stub, university.Id)
+		if err != nil {
+			return error_response(wrap_error("Only the current dean or the accreditor of record may register the key", err))
+		}
+	}
+
+	university.PublicKey = args[1]
+	err = put_asset(stub, university.Id, &university) //rewrite the university with id as key
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end register_university_key")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Export VC - a certificate as a W3C Verifiable Credential, with the graduate portfolio access rules
+//
+// The credential is returned unsigned, the university signs it off-chain into a VC-JWT with vc.Sign and the private
+// half of the key it registered, which never reaches the peers.
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// Returns - the unsigned credential JSON-LD document
+// ============================================================================================================================
+func export_vc(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_graduate_reader(stub, Graduate{Id: certificate.GraduateId}, []Certificate{certificate})
+	if err != nil {
+		return error_response(err)
+	}
+
+	credential, err := build_certificate_credential(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+
+	credentialAsBytes, _ := json.Marshal(credential) //convert to array of bytes
+	return shim.Success(credentialAsBytes)
+}
@@ -0,0 +1,563 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Credit Transfer - a receiving university recognizes courses of a transcript issued by another university
+//
+// The graduate grants the receiving university a look at their transcript, the receiving university records the
+// equivalences and the source university then confirms or rejects them. Recognitions are indexed by graduate and by
+// both universities.
+// ============================================================================================================================
+
+// ----- Credit Recognition Payload ----- //
+type CreditRecognitionPayload struct {
+	Id                    string             `json:"id"`
+	CertificateId         string             `json:"certificate_id"`
+	ReceivingUniversityId string             `json:"receiving_university_id"`
+	ReceivingProgramId    string             `json:"receiving_program_id"`
+	Items                 []RecognizedCourse `json:"items"`
+}
+
+var credit_recognition_schema = PayloadSchema{
+	Name: "credit_recognition",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of the recognition"},
+		{Name: "certificate_id", Type: "string", Required: true, MaxLength: 64, Description: "Certificate whose transcript holds the courses"},
+		{Name: "receiving_university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university recognizing the credits"},
+		{Name: "receiving_program_id", Type: "string", MaxLength: 64, Description: "Program of the receiving university the credits count towards"},
+		{Name: "items", Type: "array", Required: true, Description: "Equivalences, [{sourceCode, sourceTerm, targetCode, targetName, creditHours}]"},
+	},
+}
+
+// ========================================================
+// Get Credit Recognition - get a credit recognition from ledger
+// ========================================================
+func get_credit_recognition(stub shim.ChaincodeStubInterface, id string) (CreditRecognition, error) {
+	var recognition CreditRecognition
+	recognitionKey, _ := stub.CreateCompositeKey("credit_recognition", []string{id})
+	recognitionAsBytes, err := stub.GetState(recognitionKey)
+	if err != nil {
+		return recognition, errors.New("Failed to get credit recognition - " + id)
+	}
+	if recognitionAsBytes == nil {
+		return recognition, new_error(CodeNotFound, "Credit recognition does not exist - "+id)
+	}
+	err = json.Unmarshal(recognitionAsBytes, &recognition) //un stringify it aka JSON.parse()
+	if err != nil {
+		return recognition, errors.New("Credit recognition is not readable - " + id)
+	}
+	return recognition, nil
+}
+
+// ========================================================
+// Get Credit Consent - get the consent a graduate granted a receiving university over a certificate
+// ========================================================
+func get_credit_consent(stub shim.ChaincodeStubInterface, certificate_id string, receiving_university_id string) (CreditConsent, error) {
+	var consent CreditConsent
+	consentKey, _ := stub.CreateCompositeKey("credit_consent", []string{certificate_id, receiving_university_id})
+	consentAsBytes, err := stub.GetState(consentKey)
+	if err != nil {
+		return consent, errors.New("Failed to get credit consent - " + certificate_id)
+	}
+	if consentAsBytes == nil {
+		return consent, new_error(CodeNotFound, "The graduate has not granted university '"+receiving_university_id+"' consent over certificate '"+certificate_id+"'")
+	}
+	err = json.Unmarshal(consentAsBytes, &consent) //un stringify it aka JSON.parse()
+	if err != nil {
+		return consent, errors.New("Credit consent is not readable - " + certificate_id)
+	}
+	return consent, nil
+}
+
+// ========================================================
+// Put Credit Consent - store a credit consent
+// ========================================================
+func put_credit_consent(stub shim.ChaincodeStubInterface, consent CreditConsent) error {
+	consentKey, _ := stub.CreateCompositeKey("credit_consent", []string{consent.CertificateId, consent.ReceivingUniversityId})
+	return put_asset(stub, consentKey, &consent)
+}
+
+// ========================================================
+// Put Credit Recognition - store a credit recognition
+// ========================================================
+func put_credit_recognition(stub shim.ChaincodeStubInterface, recognition CreditRecognition) error {
+	recognitionKey, _ := stub.CreateCompositeKey("credit_recognition", []string{recognition.Id})
+	return put_asset(stub, recognitionKey, &recognition)
+}
+
+// ========================================================
+// Index Credit Recognition - list a recognition under its graduate and both universities
+// ========================================================
+func index_credit_recognition(stub shim.ChaincodeStubInterface, recognition CreditRecognition) error {
+	indexes := [][]string{
+		{"recognition~graduate", recognition.GraduateId},
+		{"recognition~university", recognition.SourceUniversityId},
+		{"recognition~university", recognition.ReceivingUniversityId},
+	}
+	for _, index := range indexes {
+		if len(index[1]) == 0 {
+			continue //certificates issued before the graduate registry have no graduate
+		}
+		indexKey, err := stub.CreateCompositeKey(index[0], []string{index[1], recognition.Id})
+		if err != nil {
+			return err
+		}
+		err = stub.PutState(indexKey, []byte{0x00}) //the key is the index, the value is irrelevant
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ========================================================
+// Check Recognized Courses - every item maps a passed or exempt course of the transcript and grants no more than it earned
+//
+// Mismatches get one error for the whole list, so the items never tell which courses the transcript holds.
+// ========================================================
+func check_recognized_courses(items []RecognizedCourse, transcript Transcript) []FieldError {
+	var field_errors []FieldError
+	if len(items) == 0 {
+		field_errors = append(field_errors, FieldError{Field: "items", Message: "must list at least one course"})
+	}
+	mismatch := false
+	for i, item := range items {
+		field := "items[" + strconv.Itoa(i) + "]"
+		if len(item.TargetCode) == 0 || len(item.TargetName) == 0 {
+			field_errors = append(field_errors, FieldError{Field: field, Message: "needs a targetCode and a targetName"})
+		}
+		matched := false
+		for _, course := range transcript.Courses {
+			if course.Code == item.SourceCode && course.Term == item.SourceTerm {
+				matched = course.Status != "failed" && item.CreditHours > 0 && item.CreditHours <= course.CreditHours
+				break
+			}
+		}
+		mismatch = mismatch || !matched
+	}
+	if mismatch {
+		field_errors = append(field_errors, FieldError{Field: "items", Message: "must each match a passed or exempt course of the transcript with at least their credit hours"})
+	}
+	return field_errors
+}
+
+// ========================================================
+// Require University Reader - admins, accreditors and the dean or a registrar of the university
+// ========================================================
+func require_university_reader(stub shim.ChaincodeStubInterface, university University) error {
+	if require_admin(stub) == nil {
+		return nil
+	}
+	if _, err := get_creator_accreditor(stub); err == nil {
+		return nil
+	}
+	return require_staff(stub, university)
+}
+
+// ============================================================================================================================
+// Grant Credit Consent - the graduate lets a receiving university look at the transcript of a certificate, once
+//
+// The graduate proves their document from an identity of their own, the receiving university holds the document of
+// its applicants and so cannot grant itself consent. A grant is used up by the recognition it allows.
+//
+// Transient - "cpf" or "passport" of the graduate of the certificate
+//
+// Inputs - Array of Strings
+//
+//	0              | 1
+//	certificate_id | receiving_university_id
+//	"c123"         | "u456"
+//
+// ============================================================================================================================
+func grant_credit_consent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting grant_credit_consent")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	certificate_id := args[0]
+
+	err = proves_graduate(stub, []string{certificate_id})
+	if err != nil {
+		return error_response(err)
+	}
+	receiving, err := get_university(stub, args[1])
+	if err != nil {
+		return error_response(err)
+	}
+
+	//the grant must come from outside the receiving university, which knows the document of its applicants
+	identity, err := get_creator(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	if require_staff(stub, receiving) == nil {
+		return error_response(new_error(CodeUnauthorized, "University '"+receiving.Id+"' cannot grant itself consent"))
+	}
+	if len(receiving.CurrentTerm) > 0 {
+		term, err := get_dean_term(stub, receiving.Id, receiving.CurrentTerm)
+		if err != nil {
+			return error_response(err)
+		}
+		if strings.HasPrefix(term.Identity, identity.MspId+":") {
+			return error_response(new_error(CodeUnauthorized, "University '"+receiving.Id+"' cannot grant itself consent, the graduate grants it from another MSP"))
+		}
+	}
+
+	//an unused grant stays as it is
+	consent, err := get_credit_consent(stub, certificate_id, receiving.Id)
+	if err == nil && len(consent.RecognitionId) == 0 {
+		return error_response(new_error(CodeAlreadyExists, "University '"+receiving.Id+"' already holds an unused consent over certificate '"+certificate_id+"'"))
+	}
+
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	consent = CreditConsent{}
+	consent.ObjectType = "credit_consent"
+	consent.SchemaVersion = CreditConsentSchemaVersion
+	consent.CertificateId = certificate_id
+	consent.ReceivingUniversityId = receiving.Id
+	consent.GrantedBy = identity.Id
+	consent.GrantedAt = now.Format(time.RFC3339)
+	err = put_credit_consent(stub, consent)
+	if err != nil {
+		fmt.Println("Could not store credit consent")
+		return error_response(err)
+	}
+
+	fmt.Println("- end grant_credit_consent")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Request Credit Recognition - the receiving university records the courses it accepts, by its dean or a registrar
+//
+// The graduate consents beforehand with grant_credit_consent, each request uses up one grant.
+//
+// Inputs - JSON payload validated against credit_recognition_schema
+//
+//	{
+//		"id": "r123",
+//		"certificate_id": "c123",
+//		"receiving_university_id": "u456",
+//		"receiving_program_id": "p456",
+//		"items": [{"sourceCode": "MAT101", "sourceTerm": "2013/1", "targetCode": "CAL1", "targetName": "Cálculo 1", "creditHours": 60}]
+//	}
+//
+// ============================================================================================================================
+func request_credit_recognition(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting request_credit_recognition")
+
+	//input sanitation
+	var payload CreditRecognitionPayload
+	err = parse_payload(args, credit_recognition_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//the receiving university acts through its own staff and must be able to admit students
+	receiving, err := get_university(stub, payload.ReceivingUniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_staff(stub, receiving)
+	if err != nil {
+		return error_response(err)
+	}
+	if receiving.Status != "active" {
+		return error_response(new_error(CodeConflict, "University '"+receiving.Id+"' is "+receiving.Status+" and cannot recognize credits"))
+	}
+
+	//the transcript is only looked at with the consent of its graduate
+	consent, err := get_credit_consent(stub, payload.CertificateId, receiving.Id)
+	if err != nil {
+		if as_chaincode_error(err).Code == CodeNotFound {
+			return error_response(new_error(CodeUnauthorized, "The graduate must grant consent with grant_credit_consent first"))
+		}
+		return error_response(err)
+	}
+	if len(consent.RecognitionId) > 0 {
+		return error_response(new_error(CodeUnauthorized, "The consent of the graduate was used by recognition '"+consent.RecognitionId+"', they must grant it again"))
+	}
+	transcript, err := get_transcript(stub, payload.CertificateId)
+	if err != nil {
+		return error_response(err)
+	}
+	if receiving.Id == transcript.UniversityId {
+		return error_response(new_error(CodeInvalidArgument, "A university cannot recognize its own credits"))
+	}
+	field_errors := check_recognized_courses(payload.Items, transcript)
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: credit_recognition_schema.Name, Fields: field_errors})
+	}
+	if len(payload.ReceivingProgramId) > 0 {
+		_, err = get_program(stub, receiving.Id, payload.ReceivingProgramId)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+
+	//check if recognition already exists
+	_, err = get_credit_recognition(stub, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This credit recognition already exists - "+payload.Id))
+	}
+
+	identity, err := get_creator(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	var recognition CreditRecognition
+	recognition.ObjectType = "credit_recognition"
+	recognition.SchemaVersion = CreditRecognitionSchemaVersion
+	recognition.Id = payload.Id
+	recognition.CertificateId = transcript.CertificateId
+	recognition.GraduateId = transcript.GraduateId
+	recognition.SourceUniversityId = transcript.UniversityId
+	recognition.ReceivingUniversityId = receiving.Id
+	recognition.ReceivingProgramId = payload.ReceivingProgramId
+	recognition.Items = payload.Items
+	for _, item := range payload.Items {
+		recognition.TotalCreditHours += item.CreditHours
+	}
+	recognition.Status = "pending"
+	recognition.RequestedBy = identity.Id
+	recognition.RequestedAt = now.Format(time.RFC3339)
+
+	err = put_credit_recognition(stub, recognition)
+	if err != nil {
+		fmt.Println("Could not store credit recognition")
+		return error_response(err)
+	}
+	err = index_credit_recognition(stub, recognition)
+	if err != nil {
+		return error_response(err)
+	}
+	consent.RecognitionId = recognition.Id
+	err = put_credit_consent(stub, consent)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end request_credit_recognition")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Decide Credit Recognition - the source university confirms or rejects a pending recognition, by its dean or a registrar
+//
+// Inputs - Array of Strings
+//
+//	0              | 1                       | 2
+//	recognition_id | decision                | reason
+//
+// "r123"          | "confirmed"/"rejected"  | "Transcript matches our records"
+// ============================================================================================================================
+func decide_credit_recognition(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting decide_credit_recognition")
+
+	if len(args) != 3 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	decision := args[1]
+	if decision != "confirmed" && decision != "rejected" {
+		return error_response(new_error(CodeInvalidArgument, "Decision must be confirmed or rejected"))
+	}
+
+	recognition, err := get_credit_recognition(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	if recognition.Status != "pending" {
+		return error_response(new_error(CodeConflict, "Credit recognition '"+recognition.Id+"' was already "+recognition.Status))
+	}
+
+	//the university that issued the transcript vouches for it, through whoever answers for it today
+	source, err := get_university(stub, recognition.SourceUniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	responsible, err := get_responsible_university(stub, source)
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_staff(stub, responsible)
+	if err != nil {
+		return error_response(err)
+	}
+
+	identity, err := get_creator(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	recognition.Status = decision
+	recognition.DecidedBy = identity.Id
+	recognition.DecidedAt = now.Format(time.RFC3339)
+	recognition.Reason = args[2]
+	err = put_credit_recognition(stub, recognition)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end decide_credit_recognition")
+	return shim.Success(nil)
+}
+
+// ========================================================
+// Read Indexed Recognitions - every recognition listed under an index key
+// ========================================================
+func read_indexed_recognitions(stub shim.ChaincodeStubInterface, index string, id string) ([]CreditRecognition, error) {
+	var recognitions []CreditRecognition
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(index, []string{id})
+	if err != nil {
+		return recognitions, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return recognitions, err
+		}
+		_, keyParts, err := stub.SplitCompositeKey(record.Key)
+		if err != nil {
+			return recognitions, err
+		}
+		recognition, err := get_credit_recognition(stub, keyParts[1])
+		if err != nil {
+			return recognitions, err
+		}
+		recognitions = append(recognitions, recognition)
+	}
+	return recognitions, nil
+}
+
+// ============================================================================================================================
+// Read Recognitions By Graduate - every credit recognition of a graduate, with the graduate portfolio access rules
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	graduate_id
+//	"9f86d081884c7d65..."
+//
+// ============================================================================================================================
+func read_recognitions_by_graduate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	graduate, err := get_graduate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	var certificates []Certificate
+	for _, certificate_id := range graduate.Certificates {
+		certificate, err := get_certificate(stub, certificate_id)
+		if err != nil {
+			return error_response(new_error(CodeInternal, "An error occurred while retrieving certificates"))
+		}
+		certificates = append(certificates, certificate)
+	}
+	err = require_graduate_reader(stub, graduate, certificates)
+	if err != nil {
+		return error_response(err)
+	}
+
+	recognitions, err := read_indexed_recognitions(stub, "recognition~graduate", graduate.Id)
+	if err != nil {
+		return error_response(err)
+	}
+	recognitionsAsBytes, _ := json.Marshal(recognitions) //convert to array of bytes
+	return shim.Success(recognitionsAsBytes)
+}
+
+// ============================================================================================================================
+// Read Recognitions By University - every credit recognition a university requested or was asked to confirm
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_recognitions_by_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_university_reader(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+
+	recognitions, err := read_indexed_recognitions(stub, "recognition~university", university.Id)
+	if err != nil {
+		return error_response(err)
+	}
+	recognitionsAsBytes, _ := json.Marshal(recognitions) //convert to array of bytes
+	return shim.Success(recognitionsAsBytes)
+}
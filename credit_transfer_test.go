@@ -0,0 +1,145 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreditRecognitionNeedsConsent(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_transcript", `{"certificate_id": "c1", "courses": [
+		{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 8, "creditHours": 60, "status": "passed"},
+		{"code": "FIS101", "name": "Physics", "term": "2013/1", "grade": 3, "creditHours": 60, "status": "failed"}]}`))
+	stub.add_university(t, "u2", "11.444.777/0001-61", "Org2MSP")
+
+	//the receiving university holds the document but cannot grant itself consent
+	grants := []struct {
+		name   string
+		caller string
+		cpf    string
+		code   string
+	}{
+		{"without a document", "Org9MSP:maria", "", CodeUnauthorized},
+		{"with someone else's document", "Org9MSP:maria", "111.444.777-35", CodeUnauthorized},
+		{"by the receiving dean", "Org2MSP:dean", test_cpf, CodeUnauthorized},
+		{"from the receiving MSP", "Org2MSP:clerk", test_cpf, CodeUnauthorized},
+	}
+	for _, grant := range grants {
+		t.Run(grant.name, func(t *testing.T) {
+			transient := map[string]string{}
+			if grant.cpf != "" {
+				transient["cpf"] = grant.cpf
+			}
+			mspId, cn := split_identity(grant.caller)
+			fails(t, stub.as(mspId, cn).with(transient).invoke("grant_credit_consent", "c1", "u2"), grant.code)
+		})
+	}
+
+	request := func(id string, item string) string {
+		return `{"id": "` + id + `", "certificate_id": "c1", "receiving_university_id": "u2", "items": [` + item + `]}`
+	}
+	passed := `{"sourceCode": "MAT101", "sourceTerm": "2013/1", "targetCode": "CAL1", "targetName": "Calculo 1", "creditHours": 60}`
+	failed := `{"sourceCode": "FIS101", "sourceTerm": "2013/1", "targetCode": "FIS1", "targetName": "Fisica 1", "creditHours": 60}`
+	missing := `{"sourceCode": "QUI101", "sourceTerm": "2013/1", "targetCode": "QUI1", "targetName": "Quimica 1", "creditHours": 60}`
+	too_many := `{"sourceCode": "MAT101", "sourceTerm": "2013/1", "targetCode": "CAL1", "targetName": "Calculo 1", "creditHours": 90}`
+	dean := func() *test_stub { return stub.as("Org2MSP", "dean").with(nil) }
+	graduate := func() *test_stub { return stub.as("Org9MSP", "maria").with(map[string]string{"cpf": test_cpf}) }
+
+	//without a grant nothing about the transcript comes back, whatever the items
+	for _, item := range []string{passed, failed, missing} {
+		fails(t, dean().invoke("request_credit_recognition", request("r1", item)), CodeUnauthorized)
+	}
+
+	ok(t, graduate().invoke("grant_credit_consent", "c1", "u2"))
+	fails(t, graduate().invoke("grant_credit_consent", "c1", "u2"), CodeAlreadyExists)
+
+	//every mismatch reads the same and names the whole list
+	var mismatch string
+	for _, item := range []string{failed, missing, too_many, passed + `, ` + missing} {
+		chaincodeError := fails(t, dean().invoke("request_credit_recognition", request("r1", item)), CodeInvalidArgument)
+		if len(chaincodeError.Fields) != 1 || chaincodeError.Fields[0].Field != "items" {
+			t.Fatalf("mismatch error points at single items - %+v", chaincodeError.Fields)
+		}
+		message := chaincodeError.Fields[0].Message
+		if mismatch != "" && message != mismatch {
+			t.Fatalf("mismatch errors differ - %q and %q", mismatch, message)
+		}
+		mismatch = message
+		if strings.Contains(message, "60") || strings.Contains(message, "failed") {
+			t.Fatalf("mismatch error reveals the transcript - %q", message)
+		}
+	}
+
+	//a grant allows one recognition
+	ok(t, dean().invoke("request_credit_recognition", request("r1", passed)))
+	fails(t, dean().invoke("request_credit_recognition", request("r2", passed)), CodeUnauthorized)
+	ok(t, graduate().invoke("grant_credit_consent", "c1", "u2"))
+	ok(t, dean().invoke("request_credit_recognition", request("r2", passed)))
+}
+
+func TestCreditRecognition(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_transcript", `{"certificate_id": "c1", "courses": [
+		{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 8, "creditHours": 60, "status": "passed"},
+		{"code": "FIS101", "name": "Physics", "term": "2013/1", "grade": 3, "creditHours": 60, "status": "failed"}]}`))
+	stub.add_university(t, "u2", "11.444.777/0001-61", "Org2MSP")
+
+	request := func(item string) string {
+		return `{"id": "r1", "certificate_id": "c1", "receiving_university_id": "u2", "items": [` + item + `]}`
+	}
+	passed := `{"sourceCode": "MAT101", "sourceTerm": "2013/1", "targetCode": "CAL1", "targetName": "Calculo 1", "creditHours": 60}`
+	ok(t, stub.as("Org9MSP", "maria").with(map[string]string{"cpf": test_cpf}).invoke("grant_credit_consent", "c1", "u2"))
+	tests := []struct {
+		name   string
+		caller string
+		item   string
+		code   string
+	}{
+		{"by a stranger", "Org9MSP:mallory", passed, CodeUnauthorized},
+		{"failed course", "Org2MSP:dean", `{"sourceCode": "FIS101", "sourceTerm": "2013/1", "targetCode": "FIS1", "targetName": "Fisica 1", "creditHours": 60}`, CodeInvalidArgument},
+		{"missing course", "Org2MSP:dean", `{"sourceCode": "QUI101", "sourceTerm": "2013/1", "targetCode": "QUI1", "targetName": "Quimica 1", "creditHours": 60}`, CodeInvalidArgument},
+		{"more hours than earned", "Org2MSP:dean", `{"sourceCode": "MAT101", "sourceTerm": "2013/1", "targetCode": "CAL1", "targetName": "Calculo 1", "creditHours": 90}`, CodeInvalidArgument},
+		{"passed course", "Org2MSP:dean", passed, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).with(nil).invoke("request_credit_recognition", request(test.item))
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	//the source university vouches for its transcript, once
+	fails(t, stub.as("Org2MSP", "dean").with(nil).invoke("decide_credit_recognition", "r1", "confirmed", "Matches"), CodeUnauthorized)
+	ok(t, stub.as("Org1MSP", "joao").invoke("decide_credit_recognition", "r1", "confirmed", "Matches"))
+	fails(t, stub.as("Org1MSP", "joao").invoke("decide_credit_recognition", "r1", "rejected", "Changed our mind"), CodeConflict)
+	recognition, err := get_credit_recognition(stub, "r1")
+	if err != nil || recognition.Status != "confirmed" || len(recognition.Items) != 1 {
+		t.Fatalf("recognition %+v %v", recognition, err)
+	}
+}
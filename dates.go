@@ -0,0 +1,107 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ----- Date Policy ----- //
+// how far the declared conferral date of a certificate may be from its issuance, in days, 0 means no limit on that side
+// and the day of issuance itself is always allowed
+type DatePolicy struct {
+	MaxBackdateDays int `json:"maxBackdateDays"` //conferral at most this many days before issuance, 0 means no limit
+	MaxFutureDays   int `json:"maxFutureDays"`   //conferral at most this many days after issuance, 0 means no limit
+}
+
+// ========================================================
+// Parse Date - read a date sent by a client
+//
+// Accepts YYYY-MM-DD, the ISO-8601 basic form YYYYMMDD, ISO-8601 date times with an offset (RFC 3339) or without one,
+// read as UTC, and the 13 digit epoch milliseconds older clients send. Other runs of digits are refused, never guessed.
+// ========================================================
+func parse_date(str string) (time.Time, error) {
+	if day, err := time.Parse("2006-01-02", str); err == nil {
+		return day, nil
+	}
+	if at, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return at, nil
+	}
+	if at, err := time.Parse("2006-01-02T15:04:05", str); err == nil {
+		return at, nil //no offset, fractional seconds are read as well
+	}
+	if len(str) > 0 && len(only_digits(str)) == len(str) {
+		if len(str) == 8 {
+			if day, err := time.Parse("20060102", str); err == nil {
+				return day, nil
+			}
+		}
+		if len(str) == 13 {
+			epoch, _ := strconv.ParseInt(str, 10, 64)
+			return time.Unix(0, epoch*int64(time.Millisecond)).UTC(), nil
+		}
+	}
+	return time.Time{}, new_error(CodeInvalidArgument, "'"+str+"' is not a YYYY-MM-DD or YYYYMMDD date, an ISO-8601 date time or 13 digit epoch milliseconds")
+}
+
+// ========================================================
+// Canonical Date - the stored form of a date, YYYY-MM-DD
+//
+// Date times keep the day they name in their own offset, epoch values and date times without an offset the UTC day.
+// ========================================================
+func canonical_date(str string) (string, error) {
+	date, err := parse_date(str)
+	if err != nil {
+		return str, err
+	}
+	return date.Format("2006-01-02"), nil
+}
+
+// ========================================================
+// Check Date Policy - the conferral day is within the configured distance of the issuance day
+// ========================================================
+func check_date_policy(policy DatePolicy, day string, issuedAt time.Time) error {
+	conferral, err := parse_day(day)
+	if err != nil {
+		return err
+	}
+	issued, _ := parse_day(issuedAt.UTC().Format("2006-01-02"))
+	days := int(conferral.Sub(issued).Hours() / 24)
+	if policy.MaxFutureDays > 0 && days > policy.MaxFutureDays {
+		return new_error(CodeInvalidArgument, "Date "+day+" is "+strconv.Itoa(days)+" days after issuance, at most "+strconv.Itoa(policy.MaxFutureDays)+" are allowed")
+	}
+	if policy.MaxBackdateDays > 0 && -days > policy.MaxBackdateDays {
+		return new_error(CodeInvalidArgument, "Date "+day+" is "+strconv.Itoa(-days)+" days before issuance, at most "+strconv.Itoa(policy.MaxBackdateDays)+" are allowed")
+	}
+	return nil
+}
+
+// ========================================================
+// Certificate Issued At - when a certificate was written, from the record or, before v9, from its history
+// ========================================================
+func certificate_issued_at(stub shim.ChaincodeStubInterface, certificate Certificate) (time.Time, error) {
+	if len(certificate.IssuedAt) > 0 {
+		return time.Parse(time.RFC3339Nano, certificate.IssuedAt)
+	}
+	return get_issued_at(stub, certificate.Id)
+}
@@ -0,0 +1,89 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanonicalDate(t *testing.T) {
+	tests := []struct {
+		input string
+		day   string
+		code  string
+	}{
+		{"2017-08-04", "2017-08-04", ""},
+		{"20170804", "2017-08-04", ""},
+		{"2017-08-04T23:30:00-03:00", "2017-08-04", ""},
+		{"2017-08-04T01:30:00.123+09:00", "2017-08-04", ""},
+		{"2017-08-04T23:30:00", "2017-08-04", ""},
+		{"2017-08-04T23:30:00.5", "2017-08-04", ""},
+		{"2017-08-04T24:30:00", "", CodeInvalidArgument},
+		{"1501810298042", "2017-08-04", ""},
+		{"20171304", "", CodeInvalidArgument},
+		{"1501810298", "", CodeInvalidArgument},
+		{"150181029804", "", CodeInvalidArgument},
+		{"12345", "", CodeInvalidArgument},
+		{"04/08/2017", "", CodeInvalidArgument},
+		{"", "", CodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			day, err := canonical_date(test.input)
+			if test.code != "" {
+				if err == nil || as_chaincode_error(err).Code != test.code {
+					t.Fatalf("expected %s, got %q %v", test.code, day, err)
+				}
+				return
+			}
+			if err != nil || day != test.day {
+				t.Fatalf("expected %s, got %q %v", test.day, day, err)
+			}
+		})
+	}
+}
+
+func TestCheckDatePolicy(t *testing.T) {
+	issuedAt := time.Date(2017, 8, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		policy DatePolicy
+		day    string
+		valid  bool
+	}{
+		{"same day", DatePolicy{}, "2017-08-04", true},
+		{"no backdate limit", DatePolicy{}, "1990-01-01", true},
+		{"within the backdate limit", DatePolicy{MaxBackdateDays: 30}, "2017-07-05", true},
+		{"past the backdate limit", DatePolicy{MaxBackdateDays: 30}, "2017-07-04", false},
+		{"no future limit", DatePolicy{}, "2030-01-01", true},
+		{"within the future limit", DatePolicy{MaxFutureDays: 2}, "2017-08-06", true},
+		{"past the future limit", DatePolicy{MaxFutureDays: 2}, "2017-08-07", false},
+		{"same day under both limits", DatePolicy{MaxBackdateDays: 1, MaxFutureDays: 1}, "2017-08-04", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := check_date_policy(test.policy, test.day, issuedAt)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got %v", test.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ========================================================
+// Get Dean Term - get a dean term of a university from ledger
+// ========================================================
+func get_dean_term(stub shim.ChaincodeStubInterface, university_id string, term_id string) (DeanTerm, error) {
+	var term DeanTerm
+	termKey, _ := stub.CreateCompositeKey("dean_term", []string{university_id, term_id})
+	termAsBytes, err := stub.GetState(termKey)
+	if err != nil {
+		return term, errors.New("Failed to get dean term - " + term_id)
+	}
+	if termAsBytes == nil {
+		return term, new_error(CodeNotFound, "Dean term does not exist - "+term_id)
+	}
+	err = json.Unmarshal(termAsBytes, &term) //un stringify it aka JSON.parse()
+	if err != nil {
+		return term, errors.New("Dean term is not readable - " + term_id)
+	}
+	return term, nil
+}
+
+// ========================================================
+// Put Dean Term - store a dean term
+// ========================================================
+func put_dean_term(stub shim.ChaincodeStubInterface, term DeanTerm) error {
+	termKey, _ := stub.CreateCompositeKey("dean_term", []string{term.UniversityId, term.Id})
+	return put_asset(stub, termKey, &term)
+}
+
+// ========================================================
+// Start Dean Term - end the current term of the university and open one for the new dean
+//
+// The caller stores the university, which gets the new dean and current term.
+// ========================================================
+func start_dean_term(stub shim.ChaincodeStubInterface, university *University, name string, identity string) error {
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return err
+	}
+
+	if len(university.CurrentTerm) > 0 {
+		current, err := get_dean_term(stub, university.Id, university.CurrentTerm)
+		if err != nil {
+			return err
+		}
+		current.EndDate = now.Format(time.RFC3339Nano)
+		err = put_dean_term(stub, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	var term DeanTerm
+	term.ObjectType = "dean_term"
+	term.SchemaVersion = DeanTermSchemaVersion
+	term.Id = stub.GetTxID() //one term starts per transaction
+	term.UniversityId = university.Id
+	term.Name = name
+	term.Identity = identity
+	term.StartDate = now.Format(time.RFC3339Nano)
+	err = put_dean_term(stub, term)
+	if err != nil {
+		return err
+	}
+
+	university.Dean = name
+	university.CurrentTerm = term.Id
+	return nil
+}
+
+// ========================================================
+// Check Identity Format - identities are listed as "<msp>:<common name>"
+// ========================================================
+func check_identity_format(identity string) error {
+	parts := strings.SplitN(identity, ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return new_error(CodeInvalidArgument, "Identity '"+identity+"' must be \"<msp>:<common name>\"")
+	}
+	return nil
+}
+
+// ========================================================
+// Require Signer - creator must be the dean of the current term of the university, returns that term
+// ========================================================
+func require_signer(stub shim.ChaincodeStubInterface, university University) (DeanTerm, error) {
+	var term DeanTerm
+	identity, err := get_creator(stub)
+	if err != nil {
+		return term, err
+	}
+	if len(university.CurrentTerm) == 0 {
+		return term, new_error(CodeNotFound, "University '"+university.Id+"' has no dean term on record")
+	}
+	term, err = get_dean_term(stub, university.Id, university.CurrentTerm)
+	if err != nil {
+		return term, err
+	}
+	if len(term.Identity) == 0 || term.Identity != identity.Id {
+		return term, new_error(CodeUnauthorized, "Identity '"+identity.Id+"' is not the dean of university '"+university.Id+"'")
+	}
+	return term, nil
+}
+
+// ========================================================
+// Check Dean Term At - the term must belong to the university and cover the given time
+// ========================================================
+func check_dean_term_at(stub shim.ChaincodeStubInterface, university_id string, term_id string, at time.Time) error {
+	term, err := get_dean_term(stub, university_id, term_id)
+	if err != nil {
+		return err
+	}
+	start, _ := time.Parse(time.RFC3339Nano, term.StartDate)
+	if at.Before(start) {
+		return new_error(CodeConflict, "Dean term '"+term_id+"' of "+term.Name+" only started at "+term.StartDate)
+	}
+	if len(term.EndDate) > 0 {
+		end, _ := time.Parse(time.RFC3339Nano, term.EndDate)
+		if !at.Before(end) {
+			return new_error(CodeConflict, "Dean term '"+term_id+"' of "+term.Name+" ended at "+term.EndDate)
+		}
+	}
+	return nil
+}
+
+// ========================================================
+// Get Issued At - transaction time of the first write of a key, read from its history
+// ========================================================
+func get_issued_at(stub shim.ChaincodeStubInterface, key string) (time.Time, error) {
+	resultsIterator, err := stub.GetHistoryForKey(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resultsIterator.Close()
+
+	var issuedAt time.Time
+	for resultsIterator.HasNext() {
+		historicValue, err := resultsIterator.Next()
+		if err != nil {
+			return time.Time{}, err
+		}
+		at := time.Unix(historicValue.Timestamp.Seconds, int64(historicValue.Timestamp.Nanos)).UTC()
+		if issuedAt.IsZero() || at.Before(issuedAt) {
+			issuedAt = at
+		}
+	}
+	if issuedAt.IsZero() {
+		return issuedAt, new_error(CodeNotFound, "No history for - "+key)
+	}
+	return issuedAt, nil
+}
+
+// ============================================================================================================================
+// Read Dean Terms - list all dean terms of a university
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_dean_terms(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var terms []DeanTerm
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("dean_term", []string{args[0]})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var term DeanTerm
+		json.Unmarshal(record.Value, &term) //un stringify it aka JSON.parse()
+		terms = append(terms, term)
+	}
+
+	termsAsBytes, _ := json.Marshal(terms) //convert to array of bytes
+	return shim.Success(termsAsBytes)
+}
+
+// ============================================================================================================================
+// Set Dean Identity - register the identity the dean of the current term signs with, accreditor of record or regulator
+//
+// For terms started without one, e.g. universities admitted by governance or created from positional arguments.
+// The identity of a term is set once, a dean changing identity starts a new term.
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | identity
+//
+// "u123"         | "Org1MSP:joao@uniuni.edu.br"
+// ============================================================================================================================
+func set_dean_identity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting set_dean_identity")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	err = check_identity_format(args[1])
+	if err != nil {
+		return error_response(err)
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_accreditor_of(stub, university.Id)
+	if err != nil {
+		return error_response(err)
+	}
+	if len(university.CurrentTerm) == 0 {
+		return error_response(new_error(CodeNotFound, "University '"+university.Id+"' has no dean term on record"))
+	}
+	term, err := get_dean_term(stub, university.Id, university.CurrentTerm)
+	if err != nil {
+		return error_response(err)
+	}
+	if len(term.Identity) > 0 {
+		return error_response(new_error(CodeConflict, "Dean term '"+term.Id+"' already has an identity, start a new term with set_dean"))
+	}
+
+	term.Identity = args[1]
+	err = put_dean_term(stub, term)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end set_dean_identity")
+	return shim.Success(nil)
+}
@@ -0,0 +1,130 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSetDeanIdentity(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("Org1MSP", "admin").invoke("init_accreditor", `{"id": "a2", "name": "CEE", "mspId": "CEEMSP"}`))
+	ok(t, stub.as("MECMSP", "inspector").invoke("init_university", "u2", "Ana", "Universidade Dois", "11.444.777/0001-61"))
+
+	tests := []struct {
+		name     string
+		mspId    string
+		cn       string
+		identity string
+		code     string
+	}{
+		{"bad identity", "MECMSP", "inspector", "ana", CodeInvalidArgument},
+		{"another accreditor", "CEEMSP", "inspector", "Org2MSP:ana", CodeUnauthorized},
+		{"the dean herself", "Org2MSP", "ana", "Org2MSP:ana", CodeUnauthorized},
+		{"accreditor of record", "MECMSP", "inspector", "Org2MSP:ana", ""},
+		{"set twice", "MECMSP", "inspector", "Org2MSP:other", CodeConflict},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.as(test.mspId, test.cn).invoke("set_dean_identity", "u2", test.identity)
+			if test.code != "" {
+				fails(t, res, test.code)
+			} else {
+				ok(t, res)
+			}
+		})
+	}
+
+	//the dean can act for the university now
+	university, _ := get_university(stub, "u2")
+	stub.as("Org2MSP", "ana").begin()
+	_, err := require_signer(stub, university)
+	stub.end()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTermAndRevocationTimesKeepSubseconds(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("Org1MSP", "joao").invoke("grant_delegation", `{"id": "d1", "university_id": "u1", "registrar": "Org1MSP:rita", "valid_from": "2017-01-01", "valid_until": "2017-12-31"}`))
+	changed := time.Date(2017, 8, 4, 15, 0, 0, 500000000, time.UTC)
+	stub.clock = changed
+	ok(t, stub.invoke("revoke_delegation", "u1", "d1", "Left"))
+	first, _ := get_university(stub, "u1")
+	stub.clock = changed
+	ok(t, stub.invoke("set_dean", "u1", "Joao", "Jose", "Org1MSP:jose"))
+	delegation, _ := get_delegation(stub, "u1", "d1")
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		valid bool
+	}{
+		{"earlier in the same second", changed.Add(-300 * time.Millisecond), true},
+		{"at the change", changed, false},
+		{"later in the same second", changed.Add(300 * time.Millisecond), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			termErr := check_dean_term_at(stub, "u1", first.CurrentTerm, test.at)
+			delegationErr := check_delegation_at(delegation, test.at)
+			if (termErr == nil) != test.valid || (delegationErr == nil) != test.valid {
+				t.Fatalf("expected valid %v, got term %v and delegation %v", test.valid, termErr, delegationErr)
+			}
+		})
+	}
+}
+
+func TestDeanTerms(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	fails(t, stub.as("Org9MSP", "mallory").invoke("set_dean", "u1", "Joao", "Jose", "Org1MSP:jose"), CodeUnauthorized)
+	ok(t, stub.as("Org1MSP", "joao").invoke("set_dean", "u1", "Joao", "Jose", "Org1MSP:jose"))
+
+	//only the dean in office signs
+	fails(t, stub.issue(map[string]interface{}{"id": "c2"}), CodeUnauthorized)
+	ok(t, stub.issue_as("Org1MSP", "jose", map[string]interface{}{"id": "c2"}))
+
+	var terms []DeanTerm
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("read_dean_terms", "u1")), &terms)
+	if len(terms) != 2 {
+		t.Fatalf("terms %+v", terms)
+	}
+	for _, term := range terms {
+		if (term.Identity == "Org1MSP:joao") != (len(term.EndDate) > 0) {
+			t.Fatalf("term %+v", term)
+		}
+	}
+
+	//certificates signed in an ended term stay valid
+	for _, id := range []string{"c1", "c2"} {
+		var verification struct {
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		}
+		json.Unmarshal(ok(t, stub.invoke("verify_certificate", id)), &verification)
+		if !verification.Valid {
+			t.Fatalf("%s - %v", id, verification.Problems)
+		}
+	}
+}
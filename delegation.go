@@ -0,0 +1,437 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Delegation Payload ----- //
+type DelegationPayload struct {
+	Id           string   `json:"id"`
+	UniversityId string   `json:"university_id"`
+	Registrar    string   `json:"registrar"`
+	Programs     []string `json:"programs"`
+	Campuses     []string `json:"campuses"`
+	ValidFrom    string   `json:"valid_from"`
+	ValidUntil   string   `json:"valid_until"`
+}
+
+var delegation_schema = PayloadSchema{
+	Name: "delegation",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of delegation"},
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university"},
+		{Name: "registrar", Type: "string", Required: true, MaxLength: 256, Description: "Registrar identity, \"<msp>:<common name>\""},
+		{Name: "programs", Type: "array", Description: "Ids of the programs covered, empty for all"},
+		{Name: "campuses", Type: "array", Description: "Ids of the campuses covered, empty for all"},
+		{Name: "valid_from", Type: "string", Required: true, MaxLength: 10, Description: "First valid day, YYYY-MM-DD"},
+		{Name: "valid_until", Type: "string", Required: true, MaxLength: 10, Description: "Last valid day, YYYY-MM-DD"},
+	},
+}
+
+// ----- Signer ----- //
+type Signer struct {
+	Term       DeanTerm //dean term the signature derives from
+	Identity   string   //identity that signed
+	Delegation string   //Id of the delegation used, empty when the dean signed
+}
+
+// ========================================================
+// Get Delegation - get a delegation of a university from ledger
+// ========================================================
+func get_delegation(stub shim.ChaincodeStubInterface, university_id string, delegation_id string) (Delegation, error) {
+	var delegation Delegation
+	delegationKey, _ := stub.CreateCompositeKey("delegation", []string{university_id, delegation_id})
+	delegationAsBytes, err := stub.GetState(delegationKey)
+	if err != nil {
+		return delegation, errors.New("Failed to get delegation - " + delegation_id)
+	}
+	if delegationAsBytes == nil {
+		return delegation, new_error(CodeNotFound, "Delegation does not exist - "+delegation_id)
+	}
+	err = json.Unmarshal(delegationAsBytes, &delegation) //un stringify it aka JSON.parse()
+	if err != nil {
+		return delegation, errors.New("Delegation is not readable - " + delegation_id)
+	}
+	return delegation, nil
+}
+
+// ========================================================
+// Put Delegation - store a delegation
+// ========================================================
+func put_delegation(stub shim.ChaincodeStubInterface, delegation Delegation) error {
+	delegationKey, _ := stub.CreateCompositeKey("delegation", []string{delegation.UniversityId, delegation.Id})
+	return put_asset(stub, delegationKey, &delegation)
+}
+
+// ========================================================
+// Check Delegation At - the delegation must be within its dates and not revoked at the given time
+// ========================================================
+func check_delegation_at(delegation Delegation, at time.Time) error {
+	validFrom, err := parse_day(delegation.ValidFrom)
+	if err != nil {
+		return err
+	}
+	validUntil, err := parse_day(delegation.ValidUntil)
+	if err != nil {
+		return err
+	}
+	if at.Before(validFrom) || !at.Before(validUntil.AddDate(0, 0, 1)) {
+		return new_error(CodeUnauthorized, "Delegation '"+delegation.Id+"' is only valid from "+delegation.ValidFrom+" to "+delegation.ValidUntil)
+	}
+	if delegation.Revoked {
+		revokedAt, _ := time.Parse(time.RFC3339Nano, delegation.RevokedAt)
+		if !at.Before(revokedAt) {
+			return new_error(CodeUnauthorized, "Delegation '"+delegation.Id+"' was revoked at "+delegation.RevokedAt)
+		}
+	}
+	return nil
+}
+
+// ========================================================
+// Delegation Covers - the delegation scope includes the program and one of the units, an empty scope list covers all
+//
+// units is the issuing unit and the units above it, so a campus scope covers its faculties and departments.
+// ========================================================
+func delegation_covers(delegation Delegation, program_id string, units []string) bool {
+	if !scope_includes(delegation.Programs, program_id) {
+		return false
+	}
+	if len(delegation.Campuses) == 0 {
+		return true
+	}
+	for _, unit_id := range units {
+		if scope_includes(delegation.Campuses, unit_id) {
+			return true
+		}
+	}
+	return false
+}
+
+func scope_includes(scope []string, id string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	for _, item := range scope {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ========================================================
+// Authorize Issuance - creator must be the dean in office or a registrar holding a valid delegation from them
+// ========================================================
+func authorize_issuance(stub shim.ChaincodeStubInterface, university University, delegation_id string, program_id string, units []string) (Signer, error) {
+	var signer Signer
+
+	if len(delegation_id) == 0 {
+		term, err := require_signer(stub, university)
+		if err != nil {
+			return signer, err
+		}
+		signer.Term = term
+		signer.Identity = term.Identity
+		return signer, nil
+	}
+
+	identity, err := get_creator(stub)
+	if err != nil {
+		return signer, err
+	}
+	delegation, err := get_delegation(stub, university.Id, delegation_id)
+	if err != nil {
+		return signer, err
+	}
+	if delegation.Registrar != identity.Id {
+		return signer, new_error(CodeUnauthorized, "Delegation '"+delegation_id+"' was not granted to '"+identity.Id+"'")
+	}
+	if delegation.DeanTerm != university.CurrentTerm {
+		return signer, new_error(CodeUnauthorized, "Delegation '"+delegation_id+"' lapsed with the dean term that granted it")
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return signer, err
+	}
+	err = check_delegation_at(delegation, now)
+	if err != nil {
+		return signer, err
+	}
+	if !delegation_covers(delegation, program_id, units) {
+		return signer, new_error(CodeUnauthorized, "Delegation '"+delegation_id+"' does not cover this program or campus")
+	}
+
+	term, err := get_dean_term(stub, university.Id, delegation.DeanTerm)
+	if err != nil {
+		return signer, err
+	}
+	signer.Term = term
+	signer.Identity = identity.Id
+	signer.Delegation = delegation.Id
+	return signer, nil
+}
+
+// ========================================================
+// Check Signature At - the dean term and, if used, the delegation recorded on a certificate were valid at the given time
+// ========================================================
+func check_signature_at(stub shim.ChaincodeStubInterface, certificate Certificate, at time.Time) error {
+	err := check_dean_term_at(stub, certificate.IssuedBy, certificate.DeanTerm, at)
+	if err != nil || len(certificate.Delegation) == 0 {
+		return err
+	}
+	delegation, err := get_delegation(stub, certificate.IssuedBy, certificate.Delegation)
+	if err != nil {
+		return err
+	}
+	return check_delegation_at(delegation, at)
+}
+
+// ========================================================
+// Require Staff - creator must be the dean in office or a registrar holding an active delegation from them
+// ========================================================
+func require_staff(stub shim.ChaincodeStubInterface, university University) error {
+	_, err := require_signer(stub, university)
+	if err == nil {
+		return nil
+	}
+
+	identity, err := get_creator(stub)
+	if err != nil {
+		return err
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return err
+	}
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("delegation", []string{university.Id})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		var delegation Delegation
+		json.Unmarshal(record.Value, &delegation) //un stringify it aka JSON.parse()
+		if delegation.Registrar == identity.Id && delegation.DeanTerm == university.CurrentTerm && check_delegation_at(delegation, now) == nil {
+			return nil
+		}
+	}
+	return new_error(CodeUnauthorized, "Identity '"+identity.Id+"' is neither the dean nor a registrar of university '"+university.Id+"'")
+}
+
+// ============================================================================================================================
+// Grant Delegation - the dean in office lets a registrar issue certificates on their behalf
+//
+// A delegation lapses when the granting dean term ends.
+//
+// Inputs - JSON payload validated against delegation_schema
+//
+//	{
+//		"id": "d123",
+//		"university_id": "u123",
+//		"registrar": "Org1MSP:registrar@uniuni.edu.br",
+//		"programs": [],
+//		"campuses": ["campus-centro"],
+//		"valid_from": "2017-08-01",
+//		"valid_until": "2017-12-31"
+//	}
+//
+// ============================================================================================================================
+func grant_delegation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting grant_delegation")
+
+	//input sanitation
+	var payload DelegationPayload
+	err = parse_payload(args, delegation_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	var field_errors []FieldError
+	if err = check_identity_format(payload.Registrar); err != nil {
+		field_errors = append(field_errors, FieldError{Field: "registrar", Message: error_message(err)})
+	}
+	validFrom, err := parse_day(payload.ValidFrom)
+	if err != nil {
+		field_errors = append(field_errors, FieldError{Field: "valid_from", Message: error_message(err)})
+	}
+	validUntil, err := parse_day(payload.ValidUntil)
+	if err != nil {
+		field_errors = append(field_errors, FieldError{Field: "valid_until", Message: error_message(err)})
+	} else if validUntil.Before(validFrom) {
+		field_errors = append(field_errors, FieldError{Field: "valid_until", Message: "must not be before valid_from"})
+	}
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: delegation_schema.Name, Fields: field_errors})
+	}
+
+	university, err := get_university(stub, payload.UniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	term, err := require_signer(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check if delegation already exists
+	_, err = get_delegation(stub, university.Id, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This delegation already exists - "+payload.Id))
+	}
+
+	var delegation Delegation
+	delegation.ObjectType = "delegation"
+	delegation.SchemaVersion = DelegationSchemaVersion
+	delegation.Id = payload.Id
+	delegation.UniversityId = university.Id
+	delegation.DeanTerm = term.Id
+	delegation.Registrar = payload.Registrar
+	delegation.Programs = payload.Programs
+	delegation.Campuses = payload.Campuses
+	delegation.ValidFrom = payload.ValidFrom
+	delegation.ValidUntil = payload.ValidUntil
+
+	err = put_delegation(stub, delegation)
+	if err != nil {
+		fmt.Println("Could not store delegation")
+		return error_response(err)
+	}
+
+	fmt.Println("- end grant_delegation")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Revoke Delegation - the dean in office withdraws a delegation
+//
+// Inputs - Array of Strings
+//
+//	0             | 1             | 2
+//	university_id | delegation_id | reason
+//
+// "u123"         | "d123"        | "Registrar left the university"
+// ============================================================================================================================
+func revoke_delegation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting revoke_delegation")
+
+	if len(args) != 3 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_signer(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+
+	delegation, err := get_delegation(stub, university.Id, args[1])
+	if err != nil {
+		return error_response(err)
+	}
+	if delegation.Revoked {
+		return error_response(new_error(CodeConflict, "Delegation '"+delegation.Id+"' is already revoked"))
+	}
+
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	delegation.Revoked = true
+	delegation.RevokedAt = now.Format(time.RFC3339Nano)
+	delegation.RevokedReason = args[2]
+	err = put_delegation(stub, delegation)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end revoke_delegation")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Active Delegations - list the delegations of a university usable right now
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_active_delegations(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var delegations []Delegation
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("delegation", []string{university.Id})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var delegation Delegation
+		json.Unmarshal(record.Value, &delegation) //un stringify it aka JSON.parse()
+		if delegation.DeanTerm == university.CurrentTerm && check_delegation_at(delegation, now) == nil {
+			delegations = append(delegations, delegation)
+		}
+	}
+
+	delegationsAsBytes, _ := json.Marshal(delegations) //convert to array of bytes
+	return shim.Success(delegationsAsBytes)
+}
@@ -0,0 +1,105 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRegistrarIssuesUnderDelegation(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("MECMSP", "inspector").invoke("init_program", `{"id": "p2", "university_id": "u1", "name": "History", "level": "bachelor", "field_of_study": "Humanities",
+		"credit_hours": 2800, "emec_code": "5678", "recognition_act": "Portaria 4", "recognition_valid_from": "2000-01-01", "recognition_valid_until": "2099-12-31"}`))
+
+	grants := []struct {
+		name       string
+		caller     string
+		delegation string
+		code       string
+	}{
+		{"by the registrar", "Org1MSP:registrar", `{"id": "d0", "university_id": "u1", "registrar": "Org1MSP:registrar", "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`, CodeUnauthorized},
+		{"ending before it starts", "Org1MSP:joao", `{"id": "d0", "university_id": "u1", "registrar": "Org1MSP:registrar", "valid_from": "2017-08-01", "valid_until": "2017-07-31"}`, CodeInvalidArgument},
+		{"registrar without msp", "Org1MSP:joao", `{"id": "d0", "university_id": "u1", "registrar": "registrar", "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`, CodeInvalidArgument},
+		{"for p1", "Org1MSP:joao", `{"id": "d1", "university_id": "u1", "registrar": "Org1MSP:registrar", "programs": ["p1"], "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`, ""},
+		{"from next year", "Org1MSP:joao", `{"id": "d2", "university_id": "u1", "registrar": "Org1MSP:registrar", "valid_from": "2018-01-01", "valid_until": "2018-12-31"}`, ""},
+		{"taken id", "Org1MSP:joao", `{"id": "d1", "university_id": "u1", "registrar": "Org1MSP:registrar", "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`, CodeAlreadyExists},
+	}
+	for _, grant := range grants {
+		mspId, cn := split_identity(grant.caller)
+		res := stub.as(mspId, cn).invoke("grant_delegation", grant.delegation)
+		if grant.code != "" {
+			fails(t, res, grant.code)
+		} else {
+			ok(t, res)
+		}
+	}
+
+	issuances := []struct {
+		name   string
+		caller string
+		fields map[string]interface{}
+		code   string
+	}{
+		{"without a delegation", "Org1MSP:registrar", map[string]interface{}{"id": "c1"}, CodeUnauthorized},
+		{"with another's delegation", "Org1MSP:clerk", map[string]interface{}{"id": "c1", "delegation_id": "d1"}, CodeUnauthorized},
+		{"outside the covered programs", "Org1MSP:registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1", "program_id": "p2"}, CodeUnauthorized},
+		{"before the delegation starts", "Org1MSP:registrar", map[string]interface{}{"id": "c1", "delegation_id": "d2"}, CodeUnauthorized},
+		{"with an unknown delegation", "Org1MSP:registrar", map[string]interface{}{"id": "c1", "delegation_id": "d9"}, CodeNotFound},
+		{"within the delegation", "Org1MSP:registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1"}, ""},
+	}
+	for _, test := range issuances {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.issue_as(mspId, cn, test.fields)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+	certificate, err := get_certificate(stub, "c1")
+	if err != nil || certificate.Delegation != "d1" || certificate.SignedBy != "Org1MSP:registrar" || certificate.DeanTerm == "" {
+		t.Fatalf("certificate does not record the delegation - %+v %v", certificate, err)
+	}
+
+	//revoked delegations stop working, what they signed before stays valid
+	fails(t, stub.as("Org1MSP", "registrar").invoke("revoke_delegation", "u1", "d1", "Left"), CodeUnauthorized)
+	ok(t, stub.as("Org1MSP", "joao").invoke("revoke_delegation", "u1", "d1", "Registrar left the university"))
+	fails(t, stub.as("Org1MSP", "joao").invoke("revoke_delegation", "u1", "d1", "Again"), CodeConflict)
+	fails(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c2", "delegation_id": "d1"}), CodeUnauthorized)
+	if err := check_signature_at(stub, certificate, stub.clock); err == nil {
+		t.Fatal("the revoked delegation still signs today")
+	}
+	issuedAt, _ := certificate_issued_at(stub, certificate)
+	if err := check_signature_at(stub, certificate, issuedAt); err != nil {
+		t.Fatalf("the certificate lost its signature - %v", err)
+	}
+}
+
+func TestDelegationsLapseWithTheDeanTerm(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("Org1MSP", "joao").invoke("grant_delegation", `{"id": "d1", "university_id": "u1", "registrar": "Org1MSP:registrar", "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`))
+	ok(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1"}))
+
+	ok(t, stub.as("Org1MSP", "joao").invoke("set_dean", "u1", "Joao", "Ana", "Org1MSP:ana"))
+	fails(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c2", "delegation_id": "d1"}), CodeUnauthorized)
+	ok(t, stub.issue_as("Org1MSP", "ana", map[string]interface{}{"id": "c2"}))
+}
@@ -0,0 +1,257 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package diploma generates and parses diploma XML in the AionCerts exchange layout.
+//
+// The layout borrows the element names of the MEC Diploma Digital but is a small subset of it under its own namespace.
+// It does not validate against the MEC XSD and is no Diploma Digital: the official one is built and signed with
+// ICP-Brasil tooling outside the ledger. Documents here are unsigned and parsing ignores any signature.
+//
+// Generating or reading the MEC Diploma Digital itself is out of scope: it needs the published XSD and a XAdES
+// signature with ICP-Brasil certificates, neither of which belongs in chaincode. Parse refuses MEC diplomas by name.
+package diploma
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+const (
+	Namespace = "urn:aioncerts:diploma"
+	Version   = "1"
+
+	mecNamespace = "portal.mec.gov.br/diplomadigital" //namespace of the MEC XSD, served over http and https
+)
+
+// ----- Record ----- //
+// the fields of a diploma, independent of the XML layout
+type Record struct {
+	Id             string //id of the diploma, the certificate id on the ledger
+	GraduateName   string
+	GraduateCpf    string
+	CourseName     string
+	CourseEmecCode string
+	Degree         string //grau, e.g. "Bacharelado"
+	Title          string //titulo conferido, the certificate body
+	ConclusionDate string //YYYY-MM-DD
+	UniversityName string
+	UniversityCnpj string
+	City           string //municipio of the issuing university
+	DeanName       string
+	IssueDate      string //YYYY-MM-DD
+}
+
+// ----- Diploma XML ----- //
+type document struct {
+	XMLName xml.Name    `xml:"Diploma"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Info    information `xml:"infDiploma"`
+}
+
+type information struct {
+	Version      string       `xml:"versao,attr"`
+	Id           string       `xml:"id,attr"`
+	Data         diplomaData  `xml:"DadosDiploma"`
+	Registration registration `xml:"DadosRegistro"`
+}
+
+type diplomaData struct {
+	Graduate       graduate    `xml:"Diplomado"`
+	Course         course      `xml:"DadosCurso"`
+	ConclusionDate string      `xml:"DataConclusao"`
+	University     institution `xml:"IesEmissora"`
+	Signers        []signer    `xml:"Assinantes>Assinante"`
+}
+
+type graduate struct {
+	Name string `xml:"Nome"`
+	Cpf  string `xml:"CPF"`
+}
+
+type course struct {
+	Name     string `xml:"NomeCurso"`
+	EmecCode string `xml:"CodigoCursoEMEC"`
+	Degree   string `xml:"Grau"`
+	Title    string `xml:"TituloConferido"`
+}
+
+type institution struct {
+	Name string `xml:"Nome"`
+	Cnpj string `xml:"CNPJ"`
+	City string `xml:"Municipio"`
+}
+
+type signer struct {
+	Name string `xml:"Nome"`
+	Role string `xml:"Cargo"`
+}
+
+type registration struct {
+	IssueDate string `xml:"DataExpedicaoDiploma"`
+}
+
+// grau of each program level of the catalog
+var degrees = map[string]string{
+	"bachelor":       "Bacharelado",
+	"licentiate":     "Licenciatura",
+	"technologist":   "Tecnólogo",
+	"specialization": "Especialização",
+	"master":         "Mestrado",
+	"doctorate":      "Doutorado",
+}
+
+// ========================================================
+// Degree - the MEC grau of a program level, empty for levels without a diploma
+// ========================================================
+func Degree(level string) string {
+	return degrees[level]
+}
+
+// ========================================================
+// Generate - the diploma XML of a record
+// ========================================================
+func Generate(record Record) ([]byte, error) {
+	if problems := check(record); len(problems) > 0 {
+		return nil, errors.New("diploma: missing " + strings.Join(problems, ", "))
+	}
+
+	var diploma document
+	diploma.Xmlns = Namespace
+	diploma.Info.Version = Version
+	diploma.Info.Id = record.Id
+	diploma.Info.Data.Graduate = graduate{Name: record.GraduateName, Cpf: digits(record.GraduateCpf)}
+	diploma.Info.Data.Course = course{Name: record.CourseName, EmecCode: record.CourseEmecCode, Degree: record.Degree, Title: record.Title}
+	diploma.Info.Data.ConclusionDate = record.ConclusionDate
+	diploma.Info.Data.University = institution{Name: record.UniversityName, Cnpj: digits(record.UniversityCnpj), City: record.City}
+	diploma.Info.Data.Signers = []signer{{Name: record.DeanName, Role: "Reitor"}}
+	diploma.Info.Registration.IssueDate = record.IssueDate
+
+	diplomaAsBytes, err := xml.MarshalIndent(diploma, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), diplomaAsBytes...), nil
+}
+
+// ========================================================
+// Parse - read a record from diploma XML, signed or not
+// ========================================================
+func Parse(diplomaAsBytes []byte) (Record, error) {
+	var record Record
+	var diploma document
+	err := xml.Unmarshal(diplomaAsBytes, &diploma)
+	if err != nil {
+		return record, errors.New("diploma: not a diploma XML - " + err.Error())
+	}
+	if strings.Contains(diploma.XMLName.Space, mecNamespace) {
+		return record, errors.New("diploma: a MEC Diploma Digital, only the AionCerts layout " + Namespace + " is read")
+	}
+	if diploma.XMLName.Space != Namespace {
+		return record, errors.New("diploma: namespace must be " + Namespace)
+	}
+
+	info := diploma.Info
+	record.Id = info.Id
+	record.GraduateName = info.Data.Graduate.Name
+	record.GraduateCpf = digits(info.Data.Graduate.Cpf)
+	record.CourseName = info.Data.Course.Name
+	record.CourseEmecCode = info.Data.Course.EmecCode
+	record.Degree = info.Data.Course.Degree
+	record.Title = info.Data.Course.Title
+	record.ConclusionDate = info.Data.ConclusionDate
+	record.UniversityName = info.Data.University.Name
+	record.UniversityCnpj = digits(info.Data.University.Cnpj)
+	record.City = info.Data.University.City
+	for _, s := range info.Data.Signers {
+		if s.Role == "Reitor" {
+			record.DeanName = s.Name
+		}
+	}
+	record.IssueDate = info.Registration.IssueDate
+
+	if problems := check(record); len(problems) > 0 {
+		return record, errors.New("diploma: missing " + strings.Join(problems, ", "))
+	}
+	return record, nil
+}
+
+// ========================================================
+// Compare - names of the fields that differ between two records, empty when consistent
+//
+// CPF and CNPJ are compared by their digits.
+// ========================================================
+func Compare(stored Record, uploaded Record) []string {
+	var differences []string
+	stored.GraduateCpf, uploaded.GraduateCpf = digits(stored.GraduateCpf), digits(uploaded.GraduateCpf)
+	stored.UniversityCnpj, uploaded.UniversityCnpj = digits(stored.UniversityCnpj), digits(uploaded.UniversityCnpj)
+	uploadedFields := fields(uploaded)
+	for i, field := range fields(stored) {
+		if field.value != uploadedFields[i].value {
+			differences = append(differences, field.name)
+		}
+	}
+	return differences
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// fields of a record in a fixed order, named as in the XML
+func fields(record Record) []field {
+	return []field{
+		{"id", record.Id},
+		{"Diplomado/Nome", record.GraduateName},
+		{"Diplomado/CPF", record.GraduateCpf},
+		{"DadosCurso/NomeCurso", record.CourseName},
+		{"DadosCurso/CodigoCursoEMEC", record.CourseEmecCode},
+		{"DadosCurso/Grau", record.Degree},
+		{"DadosCurso/TituloConferido", record.Title},
+		{"DataConclusao", record.ConclusionDate},
+		{"IesEmissora/Nome", record.UniversityName},
+		{"IesEmissora/CNPJ", record.UniversityCnpj},
+		{"IesEmissora/Municipio", record.City},
+		{"Assinantes/Reitor", record.DeanName},
+		{"DataExpedicaoDiploma", record.IssueDate},
+	}
+}
+
+// check lists the required fields that are empty
+func check(record Record) []string {
+	var missing []string
+	for _, f := range fields(record) {
+		if len(strings.TrimSpace(f.value)) == 0 {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
+
+// digits drops the punctuation of CPF and CNPJ numbers
+func digits(str string) string {
+	var kept []byte
+	for i := 0; i < len(str); i++ {
+		if str[i] >= '0' && str[i] <= '9' {
+			kept = append(kept, str[i])
+		}
+	}
+	return string(kept)
+}
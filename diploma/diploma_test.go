@@ -0,0 +1,87 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package diploma
+
+import (
+	"strings"
+	"testing"
+)
+
+func test_record() Record {
+	return Record{
+		Id: "c1", GraduateName: "Maria", GraduateCpf: "529.982.247-25", CourseName: "Computer Science", CourseEmecCode: "1234",
+		Degree: "Bacharelado", Title: "Bachelor of Computer Science", ConclusionDate: "2017-07-20", UniversityName: "Universidade Um",
+		UniversityCnpj: "11.222.333/0001-81", City: "Campinas", DeanName: "Joao", IssueDate: "2017-08-04",
+	}
+}
+
+func TestGenerateAndParse(t *testing.T) {
+	diplomaAsBytes, err := Generate(test_record())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(diplomaAsBytes), "mec.gov.br") {
+		t.Fatalf("the diploma claims the MEC namespace - %s", diplomaAsBytes)
+	}
+	record, err := Parse(diplomaAsBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if differences := Compare(test_record(), record); len(differences) > 0 {
+		t.Fatalf("the parsed record differs in %v", differences)
+	}
+	if record.GraduateCpf != "52998224725" {
+		t.Fatalf("the CPF was not kept as digits - %q", record.GraduateCpf)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	diplomaAsBytes, _ := Generate(test_record())
+	tests := []struct {
+		name    string
+		diploma string
+		reason  string
+	}{
+		{"not XML", "diploma", "not a diploma XML"},
+		{"MEC namespace", strings.Replace(string(diplomaAsBytes), Namespace, "http://portal.mec.gov.br/diplomadigital/arquivos-em-xsd", 1), "MEC Diploma Digital"},
+		{"MEC namespace over https", strings.Replace(string(diplomaAsBytes), Namespace, "https://portal.mec.gov.br/diplomadigital/arquivos-em-xsd", 1), "MEC Diploma Digital"},
+		{"other namespace", strings.Replace(string(diplomaAsBytes), Namespace, "urn:example:diploma", 1), "namespace must be"},
+		{"missing municipality", strings.Replace(string(diplomaAsBytes), "<Municipio>Campinas</Municipio>", "", 1), "IesEmissora/Municipio"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.diploma))
+			if err == nil || !strings.Contains(err.Error(), test.reason) {
+				t.Fatalf("expected an error about %q, got %v", test.reason, err)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	uploaded := test_record()
+	uploaded.GraduateCpf = "52998224725"
+	uploaded.City = "Sao Paulo"
+	uploaded.ConclusionDate = "2017-07-21"
+	differences := Compare(test_record(), uploaded)
+	if strings.Join(differences, ",") != "DataConclusao,IesEmissora/Municipio" {
+		t.Fatalf("differences %v", differences)
+	}
+}
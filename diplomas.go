@@ -0,0 +1,233 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jsilvaigor/AionChainCode/diploma"
+)
+
+// ========================================================
+// Build Diploma Record - the diploma data of a certificate, its issuer, program and signing dean
+// ========================================================
+func build_diploma_record(stub shim.ChaincodeStubInterface, certificate Certificate) (diploma.Record, error) {
+	var record diploma.Record
+	if len(certificate.ProgramId) == 0 {
+		return record, new_error(CodeConflict, "Certificate '"+certificate.Id+"' has no program, the diploma needs its course")
+	}
+	program, err := get_program(stub, certificate.University.Id, certificate.ProgramId)
+	if err != nil {
+		return record, err
+	}
+	if certificate.DocumentType == "passport" {
+		return record, new_error(CodeConflict, "Certificate '"+certificate.Id+"' names a passport, the diploma needs the CPF of the graduate")
+	}
+	if len(diploma.Degree(program.Level)) == 0 {
+		return record, new_error(CodeConflict, "Program '"+program.Id+"' is a "+program.Level+", which has no diploma")
+	}
+	issuer, err := get_university(stub, certificate.IssuedBy)
+	if err != nil {
+		return record, err
+	}
+	if len(issuer.Municipality) == 0 {
+		return record, new_error(CodeConflict, "University '"+issuer.Id+"' has no municipality, the diploma names it, see set_university_municipality")
+	}
+	issuedAt, err := certificate_issued_at(stub, certificate)
+	if err != nil {
+		return record, err
+	}
+
+	//the dean who signed, older certificates only name the dean of the time
+	record.DeanName = certificate.University.Dean
+	if len(certificate.DeanTerm) > 0 {
+		term, err := get_dean_term(stub, certificate.IssuedBy, certificate.DeanTerm)
+		if err != nil {
+			return record, err
+		}
+		record.DeanName = term.Name
+	}
+
+	record.Id = certificate.Id
+	record.GraduateName = certificate.Name
+	record.GraduateCpf = certificate.Document
+	record.CourseName = program.Name
+	record.CourseEmecCode = program.EmecCode
+	record.Degree = diploma.Degree(program.Level)
+	record.Title = certificate.Body
+	record.ConclusionDate, _ = canonical_date(certificate.Date) //certificates not yet migrated may hold epoch milliseconds
+	record.UniversityName = issuer.UniversityName
+	record.UniversityCnpj = issuer.Document
+	record.City = issuer.Municipality
+	record.IssueDate = issuedAt.Format("2006-01-02")
+	return record, nil
+}
+
+// ============================================================================================================================
+// Export Diploma XML - a certificate as unsigned diploma XML in the AionCerts layout, with the graduate portfolio access rules
+//
+// The layout follows the diploma package, it is no MEC Diploma Digital and does not validate against its XSD.
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// Returns - the diploma XML
+// ============================================================================================================================
+func export_diploma_xml(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_graduate_reader(stub, Graduate{Id: certificate.GraduateId}, []Certificate{certificate})
+	if err != nil {
+		return error_response(err)
+	}
+
+	record, err := build_diploma_record(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+	diplomaAsBytes, err := diploma.Generate(record)
+	if err != nil {
+		return error_response(err)
+	}
+	return shim.Success(diplomaAsBytes)
+}
+
+// ============================================================================================================================
+// Check Diploma XML - compare an uploaded diploma XML with the certificate it names
+//
+// Only the names of the differing fields are returned, never the stored values.
+//
+// Inputs - Array of strings
+//
+//	0
+//	diploma (XML)
+//	"<Diploma xmlns=...>...</Diploma>"
+//
+// Returns - { "certificateId": "c123", "consistent": false, "differences": ["DataConclusao"] }
+// ============================================================================================================================
+func check_diploma_xml(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Check struct {
+		CertificateId string   `json:"certificateId"`
+		Consistent    bool     `json:"consistent"`
+		Differences   []string `json:"differences"`
+	}
+	var check Check
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	uploaded, err := diploma.Parse([]byte(args[0]))
+	if err != nil {
+		return error_response(new_error(CodeInvalidArgument, err.Error()))
+	}
+	certificate, err := get_certificate(stub, uploaded.Id)
+	if err != nil {
+		return error_response(err)
+	}
+	stored, err := build_diploma_record(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+
+	check.CertificateId = certificate.Id
+	check.Differences = diploma.Compare(stored, uploaded)
+	check.Consistent = len(check.Differences) == 0
+	checkAsBytes, _ := json.Marshal(check) //convert to array of bytes
+	return shim.Success(checkAsBytes)
+}
+
+// ============================================================================================================================
+// Read Diploma Payload - the init_cert payload for a diploma XML, the program is found by its e-MEC code
+//
+// Inputs - Array of strings
+//
+//	0                | 1
+//	diploma (XML)    | university_id
+//	"<Diploma ...>"  | "u123"
+//
+// Returns - CertificatePayload JSON, ready for init_cert
+// ============================================================================================================================
+func read_diploma_payload(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var payload CertificatePayload
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	record, err := diploma.Parse([]byte(args[0]))
+	if err != nil {
+		return error_response(new_error(CodeInvalidArgument, err.Error()))
+	}
+	university, err := get_university(stub, args[1])
+	if err != nil {
+		return error_response(err)
+	}
+	if !same_cnpj(university.Document, record.UniversityCnpj) {
+		return error_response(new_error(CodeInvalidArgument, "The diploma was issued by CNPJ "+record.UniversityCnpj+", not by university '"+university.Id+"'"))
+	}
+
+	//find the program with the course e-MEC code
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("program", []string{university.Id})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		programRecord, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var program Program
+		json.Unmarshal(programRecord.Value, &program) //un stringify it aka JSON.parse()
+		if len(program.EmecCode) > 0 && program.EmecCode == record.CourseEmecCode {
+			payload.ProgramId = program.Id
+			break
+		}
+	}
+	if len(payload.ProgramId) == 0 {
+		return error_response(new_error(CodeNotFound, "University '"+university.Id+"' has no program with e-MEC code "+record.CourseEmecCode))
+	}
+
+	payload.Id = record.Id
+	payload.Name = record.GraduateName
+	payload.Document = record.GraduateCpf
+	payload.DocumentType = "cpf"
+	payload.Body = record.Title
+	payload.City = record.City
+	payload.Date = record.ConclusionDate
+	payload.UniversityId = university.Id
+	payload.UniversityDoc = university.Document
+	payloadAsBytes, _ := json.Marshal(payload) //convert to array of bytes
+	return shim.Success(payloadAsBytes)
+}
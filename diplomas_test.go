@@ -0,0 +1,110 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jsilvaigor/AionChainCode/diploma"
+)
+
+func TestDiplomaNamesTheUniversityMunicipality(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(map[string]interface{}{"city": "Rio de Janeiro"}))
+
+	//without a municipality on record there is no diploma
+	fails(t, stub.as("Org1MSP", "joao").invoke("export_diploma_xml", "c1"), CodeConflict)
+
+	tests := []struct {
+		name   string
+		caller string
+		code   string
+	}{
+		{"stranger", "Org2MSP:mallory", CodeUnauthorized},
+		{"accreditor of record", "MECMSP:inspector", ""},
+		{"dean", "Org1MSP:joao", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			caller := strings.SplitN(test.caller, ":", 2)
+			res := stub.as(caller[0], caller[1]).invoke("set_university_municipality", "u1", "Campinas")
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	record, err := diploma.Parse(ok(t, stub.as("Org1MSP", "joao").invoke("export_diploma_xml", "c1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City != "Campinas" {
+		t.Fatalf("IesEmissora/Municipio is %q, not the municipality of the university", record.City)
+	}
+}
+
+func TestCheckAndReadDiplomaXml(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("set_university_municipality", "u1", "Campinas"))
+	exported := string(ok(t, stub.as("Org1MSP", "joao").invoke("export_diploma_xml", "c1")))
+
+	type Check struct {
+		CertificateId string   `json:"certificateId"`
+		Consistent    bool     `json:"consistent"`
+		Differences   []string `json:"differences"`
+	}
+	tests := []struct {
+		name        string
+		diploma     string
+		differences string
+		code        string
+	}{
+		{"as exported", exported, "", ""},
+		{"other conclusion date", strings.Replace(exported, "<DataConclusao>2017-07-20", "<DataConclusao>2017-07-21", 1), "DataConclusao", ""},
+		{"other municipality", strings.Replace(exported, "<Municipio>Campinas", "<Municipio>Sao Paulo", 1), "IesEmissora/Municipio", ""},
+		{"not a diploma", "<Diploma/>", "", CodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.as("Org9MSP", "anyone").invoke("check_diploma_xml", test.diploma)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			var check Check
+			json.Unmarshal(ok(t, res), &check)
+			if check.CertificateId != "c1" || check.Consistent != (test.differences == "") || strings.Join(check.Differences, ",") != test.differences {
+				t.Fatalf("check %+v", check)
+			}
+		})
+	}
+
+	var payload CertificatePayload
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("read_diploma_payload", exported, "u1")), &payload)
+	if payload.Id != "c1" || payload.ProgramId != "p1" || payload.Document != "52998224725" || payload.Date != "2017-07-20" {
+		t.Fatalf("payload %+v", payload)
+	}
+	fails(t, stub.as("Org9MSP", "anyone").invoke("read_diploma_payload", "<Diploma/>", "u1"), CodeInvalidArgument)
+}
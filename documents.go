@@ -0,0 +1,275 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// documents a graduate may be identified by, brazilians by CPF and foreigners by passport
+var document_types = map[string]bool{"cpf": true, "passport": true}
+
+// ========================================================
+// Validate CPF - the canonical CPF, 11 digits without punctuation, or why it is not one
+//
+// Accepts "123.456.789-09" or "12345678909". Both check digits must match and sequences of a single
+// repeated digit, which pass the check digit arithmetic, are rejected.
+// ========================================================
+func validate_cpf(document string) (string, error) {
+	document = strings.TrimSpace(document)
+	for i := 0; i < len(document); i++ {
+		c := document[i]
+		if (c < '0' || c > '9') && c != '.' && c != '-' {
+			return "", new_error(CodeInvalidArgument, "CPF may only have digits, dots and a dash")
+		}
+	}
+	cpf := only_digits(document)
+	if len(cpf) != 11 {
+		return "", new_error(CodeInvalidArgument, "CPF must have 11 digits")
+	}
+	if len(document) != 11 && (len(document) != 14 || document[3] != '.' || document[7] != '.' || document[11] != '-') {
+		return "", new_error(CodeInvalidArgument, "CPF must be written as 000.000.000-00 or 00000000000")
+	}
+	if strings.Count(cpf, cpf[:1]) == 11 {
+		return "", new_error(CodeInvalidArgument, "CPF "+cpf+" is a known invalid sequence")
+	}
+	if cpf_check_digit(cpf[:9]) != cpf[9] || cpf_check_digit(cpf[:10]) != cpf[10] {
+		return "", new_error(CodeInvalidArgument, "CPF check digits do not match")
+	}
+	return cpf, nil
+}
+
+// check digit of the leading digits of a CPF, weights count down to 2 from the length plus one
+func cpf_check_digit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * (len(digits) + 1 - i)
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// ========================================================
+// Validate Passport - the canonical passport number, upper case letters and digits, or why it is not one
+//
+// Spaces and dashes are dropped, numbers have 6 to 20 characters.
+// ========================================================
+func validate_passport(document string) (string, error) {
+	var kept []byte
+	document = strings.ToUpper(document)
+	for i := 0; i < len(document); i++ {
+		c := document[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return "", new_error(CodeInvalidArgument, "Passport number may only have letters and digits")
+		}
+		kept = append(kept, c)
+	}
+	if len(kept) < 6 || len(kept) > 20 {
+		return "", new_error(CodeInvalidArgument, "Passport number must have 6 to 20 letters and digits")
+	}
+	return string(kept), nil
+}
+
+// ========================================================
+// Canonical Document - validate a graduate document of the given type and return its stored form
+//
+// An empty type is a CPF, the only document before passports were accepted.
+// ========================================================
+func canonical_document(document_type string, document string) (string, error) {
+	switch document_type {
+	case "", "cpf":
+		return validate_cpf(document)
+	case "passport":
+		return validate_passport(document)
+	}
+	return "", new_error(CodeInvalidArgument, "Document type must be cpf or passport")
+}
+
+// ========================================================
+// Validate CNPJ - the canonical CNPJ, 14 digits without punctuation, or why it is not one
+//
+// Accepts "12.345.678/0001-95" or "12345678000195", with the same check digit and sequence rules as CPFs.
+// ========================================================
+func validate_cnpj(document string) (string, error) {
+	document = strings.TrimSpace(document)
+	for i := 0; i < len(document); i++ {
+		c := document[i]
+		if (c < '0' || c > '9') && c != '.' && c != '/' && c != '-' {
+			return "", new_error(CodeInvalidArgument, "CNPJ may only have digits, dots, a slash and a dash")
+		}
+	}
+	cnpj := only_digits(document)
+	if len(cnpj) != 14 {
+		return "", new_error(CodeInvalidArgument, "CNPJ must have 14 digits")
+	}
+	if len(document) != 14 && (len(document) != 18 || document[2] != '.' || document[6] != '.' || document[10] != '/' || document[15] != '-') {
+		return "", new_error(CodeInvalidArgument, "CNPJ must be written as 00.000.000/0000-00 or 00000000000000")
+	}
+	if strings.Count(cnpj, cnpj[:1]) == 14 {
+		return "", new_error(CodeInvalidArgument, "CNPJ "+cnpj+" is a known invalid sequence")
+	}
+	if cnpj_check_digit(cnpj[:12]) != cnpj[12] || cnpj_check_digit(cnpj[:13]) != cnpj[13] {
+		return "", new_error(CodeInvalidArgument, "CNPJ check digits do not match")
+	}
+	return cnpj, nil
+}
+
+// check digit of the leading digits of a CNPJ, weights count down to 2 from the right and wrap around after 9
+func cnpj_check_digit(digits string) byte {
+	sum := 0
+	weight := 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// ========================================================
+// Same CNPJ - two CNPJs name the same university, universities stored before validation may keep punctuation
+// ========================================================
+func same_cnpj(a string, b string) bool {
+	return len(only_digits(a)) > 0 && only_digits(a) == only_digits(b)
+}
+
+// ========================================================
+// Check University CNPJ - the canonical CNPJ of a university to register, valid and not registered yet
+// ========================================================
+func check_university_cnpj(stub shim.ChaincodeStubInterface, document string) (string, error) {
+	cnpj, err := validate_cnpj(document)
+	if err != nil {
+		return "", ValidationError{Schema: university_schema.Name, Fields: []FieldError{{Field: "document", Message: error_message(err)}}}
+	}
+	owner, err := find_university_by_cnpj(stub, cnpj)
+	if err != nil {
+		return "", err
+	}
+	if len(owner) > 0 {
+		return "", new_error(CodeAlreadyExists, "CNPJ "+cnpj+" is already registered by university '"+owner+"'")
+	}
+	return cnpj, nil
+}
+
+// ========================================================
+// Find University By CNPJ - the id of the university registered with a CNPJ, empty when there is none
+// ========================================================
+func find_university_by_cnpj(stub shim.ChaincodeStubInterface, cnpj string) (string, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("university~cnpj", []string{only_digits(cnpj)})
+	if err != nil {
+		return "", err
+	}
+	defer resultsIterator.Close()
+	if !resultsIterator.HasNext() {
+		return "", nil
+	}
+	indexRecord, err := resultsIterator.Next()
+	if err != nil {
+		return "", err
+	}
+	_, keyParts, err := stub.SplitCompositeKey(indexRecord.Key)
+	if err != nil {
+		return "", err
+	}
+	return keyParts[1], nil
+}
+
+// ========================================================
+// Index University CNPJ - claim the CNPJ of a university, no two universities may share one
+// ========================================================
+func index_university_cnpj(stub shim.ChaincodeStubInterface, university University) error {
+	owner, err := find_university_by_cnpj(stub, university.Document)
+	if err != nil {
+		return err
+	}
+	if owner == university.Id {
+		return nil
+	}
+	if len(owner) > 0 {
+		return new_error(CodeAlreadyExists, "CNPJ "+only_digits(university.Document)+" is already registered by university '"+owner+"'")
+	}
+	indexKey, _ := stub.CreateCompositeKey("university~cnpj", []string{only_digits(university.Document), university.Id})
+	return stub.PutState(indexKey, []byte{0x00})
+}
+
+// ========================================================
+// Canonicalize Certificate CPF - rewrite a CPF stored with punctuation in its canonical form, used by migrate_state
+//
+// Invalid CPFs are left as issued.
+// ========================================================
+func canonicalize_certificate_cpf(certificate *Certificate) {
+	if certificate.DocumentType != "cpf" {
+		return
+	}
+	if cpf, err := validate_cpf(certificate.Document); err == nil {
+		certificate.Document = cpf
+	}
+}
+
+// ============================================================================================================================
+// Read University By CNPJ - the university registered with a CNPJ
+//
+// Inputs - Array of strings
+//
+//	0
+//	cnpj
+//	"12.345.678/0001-95"
+//
+// Returns - University JSON
+// ============================================================================================================================
+func read_university_by_cnpj(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	cnpj, err := validate_cnpj(args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	university_id, err := find_university_by_cnpj(stub, cnpj)
+	if err != nil {
+		return error_response(err)
+	}
+	if len(university_id) == 0 {
+		return error_response(new_error(CodeNotFound, "No university is registered with CNPJ "+cnpj))
+	}
+	university, err := get_university(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+	universityAsBytes, _ := json.Marshal(university) //convert to array of bytes
+	return shim.Success(universityAsBytes)
+}
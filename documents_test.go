@@ -0,0 +1,246 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateCpf(t *testing.T) {
+	tests := []struct {
+		document string
+		cpf      string //empty when invalid
+	}{
+		{"529.982.247-25", "52998224725"},
+		{"52998224725", "52998224725"},
+		{" 111.444.777-35 ", "11144477735"},
+		{"529.982.247-24", ""},
+		{"529.982.247-15", ""},
+		{"111.111.111-11", ""},
+		{"00000000000", ""},
+		{"5299822472", ""},
+		{"529.982.24725", ""},
+		{"529982.247-25", ""},
+		{"529 982 247 25", ""},
+		{"529.982.247/25", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		cpf, err := validate_cpf(test.document)
+		if test.cpf == "" {
+			if err == nil || as_chaincode_error(err).Code != CodeInvalidArgument {
+				t.Errorf("%q - accepted as %q, error %v", test.document, cpf, err)
+			}
+		} else if err != nil || cpf != test.cpf {
+			t.Errorf("%q - %q %v, expected %q", test.document, cpf, err, test.cpf)
+		}
+	}
+}
+
+func TestCanonicalDocument(t *testing.T) {
+	tests := []struct {
+		documentType string
+		document     string
+		canonical    string //empty when invalid
+	}{
+		{"", "529.982.247-25", "52998224725"},
+		{"cpf", "529.982.247-25", "52998224725"},
+		{"cpf", "AB123456", ""},
+		{"passport", "ab 123-456", "AB123456"},
+		{"passport", "529.982.247-25", ""},
+		{"passport", "AB123", ""},
+		{"passport", "AB1234567890123456789", ""},
+		{"rg", "12.345.678-9", ""},
+	}
+	for _, test := range tests {
+		canonical, err := canonical_document(test.documentType, test.document)
+		if test.canonical == "" {
+			if err == nil {
+				t.Errorf("%s %q - accepted as %q", test.documentType, test.document, canonical)
+			}
+		} else if err != nil || canonical != test.canonical {
+			t.Errorf("%s %q - %q %v, expected %q", test.documentType, test.document, canonical, err, test.canonical)
+		}
+	}
+}
+
+func TestIssueGraduateDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       map[string]interface{}
+		code         string
+		field        string //offending field of the validation error
+		documentType string
+		document     string
+	}{
+		{"punctuated CPF", map[string]interface{}{"document": "529.982.247-25"}, "", "", "cpf", "52998224725"},
+		{"bare CPF", map[string]interface{}{"document": "52998224725", "document_type": "cpf"}, "", "", "cpf", "52998224725"},
+		{"passport", map[string]interface{}{"document": "ab 123-456", "document_type": "passport"}, "", "", "passport", "AB123456"},
+		{"CPF with wrong check digits", map[string]interface{}{"document": "529.982.247-24"}, CodeInvalidArgument, "document", "", ""},
+		{"repeated digits", map[string]interface{}{"document": "111.111.111-11"}, CodeInvalidArgument, "document", "", ""},
+		{"passport given as a CPF", map[string]interface{}{"document": "AB123456"}, CodeInvalidArgument, "document", "", ""},
+		{"unknown document type", map[string]interface{}{"document_type": "rg"}, CodeInvalidArgument, "document_type", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := new_issuing_ledger(t)
+			res := stub.issue(test.fields)
+			if test.code != "" {
+				err := fails(t, res, test.code)
+				if len(err.Fields) != 1 || err.Fields[0].Field != test.field {
+					t.Fatalf("fields %+v, expected %s", err.Fields, test.field)
+				}
+				return
+			}
+			ok(t, res)
+			certificate, err := get_certificate(stub, "c1")
+			if err != nil || certificate.DocumentType != test.documentType || certificate.Document != test.document {
+				t.Fatalf("stored %s %q %v, expected %s %q", certificate.DocumentType, certificate.Document, err, test.documentType, test.document)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeCertificateCpf(t *testing.T) {
+	tests := []struct {
+		documentType string
+		document     string
+		canonical    string
+	}{
+		{"cpf", "529.982.247-25", "52998224725"},
+		{"cpf", "529.982.247-24", "529.982.247-24"},
+		{"passport", "AB-123456", "AB-123456"},
+	}
+	for _, test := range tests {
+		certificate := Certificate{Document: test.document, DocumentType: test.documentType}
+		canonicalize_certificate_cpf(&certificate)
+		if certificate.Document != test.canonical {
+			t.Errorf("%s %q - %q, expected %q", test.documentType, test.document, certificate.Document, test.canonical)
+		}
+	}
+}
+
+func TestValidateCnpj(t *testing.T) {
+	tests := []struct {
+		document string
+		cnpj     string //empty when invalid
+	}{
+		{"11.222.333/0001-81", "11222333000181"},
+		{"11222333000181", "11222333000181"},
+		{" 12.345.678/0001-95 ", "12345678000195"},
+		{"11.222.333/0001-82", ""},
+		{"11.222.333/0001-71", ""},
+		{"11.111.111/1111-11", ""},
+		{"00000000000000", ""},
+		{"1122233300018", ""},
+		{"11.222.333.0001-81", ""},
+		{"11.222.333/000181", ""},
+		{"11 222 333 0001 81", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		cnpj, err := validate_cnpj(test.document)
+		if test.cnpj == "" {
+			if err == nil || as_chaincode_error(err).Code != CodeInvalidArgument {
+				t.Errorf("%q - accepted as %q, error %v", test.document, cnpj, err)
+			}
+		} else if err != nil || cnpj != test.cnpj {
+			t.Errorf("%q - %q %v, expected %q", test.document, cnpj, err, test.cnpj)
+		}
+	}
+}
+
+func TestUniversityCnpjIsUnique(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	university, _ := get_university(stub, "u1")
+	if university.Document != "11222333000181" {
+		t.Fatalf("CNPJ stored as %q", university.Document)
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		document string
+		code     string
+	}{
+		{"wrong check digits", "u2", "11.444.777/0001-62", CodeInvalidArgument},
+		{"repeated digits", "u2", "11.111.111/1111-11", CodeInvalidArgument},
+		{"CNPJ of u1 written bare", "u2", "11222333000181", CodeAlreadyExists},
+		{"CNPJ of u1 punctuated", "u2", test_cnpj, CodeAlreadyExists},
+		{"another CNPJ", "u2", "11.444.777/0001-61", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.as("MECMSP", "inspector").invoke("init_university", `{"id": "`+test.id+`", "dean": "Ana", "dean_identity": "Org2MSP:ana", "name": "Universidade Dois", "document": "`+test.document+`"}`)
+			if test.code != "" {
+				err := fails(t, res, test.code)
+				if test.code == CodeInvalidArgument && (len(err.Fields) != 1 || err.Fields[0].Field != "document") {
+					t.Fatalf("fields %+v", err.Fields)
+				}
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	readers := []struct {
+		name     string
+		document string
+		id       string
+		code     string
+	}{
+		{"punctuated", test_cnpj, "u1", ""},
+		{"bare", "11444777000161", "u2", ""},
+		{"not registered", "12.345.678/0001-95", "", CodeNotFound},
+		{"invalid", "11.222.333/0001-82", "", CodeInvalidArgument},
+	}
+	for _, test := range readers {
+		t.Run("read "+test.name, func(t *testing.T) {
+			res := stub.as("Org9MSP", "mallory").invoke("read_university_by_cnpj", test.document)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			var university University
+			json.Unmarshal(ok(t, res), &university)
+			if university.Id != test.id {
+				t.Fatalf("read university %q, expected %q", university.Id, test.id)
+			}
+		})
+	}
+}
+
+func TestSameCnpj(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"11.222.333/0001-81", "11222333000181", true},
+		{"11222333000181", "11222333000181", true},
+		{"11.222.333/0001-81", "11.444.777/0001-61", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		if same := same_cnpj(test.a, test.b); same != test.same {
+			t.Errorf("%q %q - %v, expected %v", test.a, test.b, same, test.same)
+		}
+	}
+}
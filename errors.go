@@ -0,0 +1,97 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Error Codes - stable codes of every error a function returns, clients switch on these, never on messages
+// ============================================================================================================================
+const (
+	CodeNotFound        = "NOT_FOUND"        //the record asked for does not exist
+	CodeAlreadyExists   = "ALREADY_EXISTS"   //a record with that id or unique value exists
+	CodeUnauthorized    = "UNAUTHORIZED"     //the submitter may not do this
+	CodeInvalidArgument = "INVALID_ARGUMENT" //arguments or payload are malformed, see fields
+	CodeConflict        = "CONFLICT"         //the ledger state does not allow it, e.g. a suspended university
+	CodeInternal        = "INTERNAL"         //the ledger could not be read or written
+)
+
+// ----- Chaincode Error ----- //
+// the message of every shim.Error, as JSON
+type ChaincodeError struct {
+	Code    string       `json:"code"`             //one of the error codes above
+	Message string       `json:"message"`          //human readable, may change between versions
+	Fields  []FieldError `json:"fields,omitempty"` //offending fields of INVALID_ARGUMENT errors
+}
+
+func (e ChaincodeError) Error() string {
+	errorAsBytes, _ := json.Marshal(e)
+	return string(errorAsBytes)
+}
+
+// ========================================================
+// New Error - an error with a code
+// ========================================================
+func new_error(code string, message string) error {
+	return ChaincodeError{Code: code, Message: message}
+}
+
+// ========================================================
+// As Chaincode Error - the coded form of any error
+//
+// Validation errors are INVALID_ARGUMENT with their fields, errors without a code are INTERNAL.
+// ========================================================
+func as_chaincode_error(err error) ChaincodeError {
+	switch e := err.(type) {
+	case ChaincodeError:
+		return e
+	case ValidationError:
+		return ChaincodeError{Code: CodeInvalidArgument, Message: "Invalid " + e.Schema + " payload", Fields: e.Fields}
+	}
+	return ChaincodeError{Code: CodeInternal, Message: err.Error()}
+}
+
+// ========================================================
+// Error Message - the human message of an error, to build another message or a field error from
+// ========================================================
+func error_message(err error) string {
+	return as_chaincode_error(err).Message
+}
+
+// ========================================================
+// Wrap Error - prefix the message of an error and keep its code
+// ========================================================
+func wrap_error(prefix string, err error) error {
+	wrapped := as_chaincode_error(err)
+	wrapped.Message = prefix + " - " + wrapped.Message
+	return wrapped
+}
+
+// ========================================================
+// Error Response - the shim.Error of an error, every failing function returns through here
+// ========================================================
+func error_response(err error) pb.Response {
+	return shim.Error(as_chaincode_error(err).Error())
+}
@@ -0,0 +1,108 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestAsChaincodeError(t *testing.T) {
+	fields := []FieldError{{Field: "document", Message: "CPF must have 11 digits"}}
+	tests := []struct {
+		name string
+		err  error
+		want ChaincodeError
+	}{
+		{"coded error", new_error(CodeNotFound, "Certificate 'c9' does not exist"), ChaincodeError{Code: CodeNotFound, Message: "Certificate 'c9' does not exist"}},
+		{"validation error", ValidationError{Schema: "certificate", Fields: fields}, ChaincodeError{Code: CodeInvalidArgument, Message: "Invalid certificate payload", Fields: fields}},
+		{"error without a code", errors.New("Failed to get state for c1"), ChaincodeError{Code: CodeInternal, Message: "Failed to get state for c1"}},
+		{"wrapped coded error", wrap_error("Program 'p1'", new_error(CodeConflict, "recognition expired")), ChaincodeError{Code: CodeConflict, Message: "Program 'p1' - recognition expired"}},
+		{"wrapped error without a code", wrap_error("Migration", errors.New("bad record")), ChaincodeError{Code: CodeInternal, Message: "Migration - bad record"}},
+		{"wrapped validation error", wrap_error("Course 1", ValidationError{Schema: "course", Fields: fields}), ChaincodeError{Code: CodeInvalidArgument, Message: "Course 1 - Invalid course payload", Fields: fields}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := as_chaincode_error(test.err); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("%+v, expected %+v", got, test.want)
+			}
+		})
+	}
+	if message := error_message(new_error(CodeConflict, "suspended")); message != "suspended" {
+		t.Fatalf("message %q", message)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	res := error_response(new_error(CodeNotFound, "Certificate 'c9' does not exist"))
+	if res.Status != shim.ERROR || res.Message != `{"code":"NOT_FOUND","message":"Certificate 'c9' does not exist"}` {
+		t.Fatalf("response %d %s", res.Status, res.Message)
+	}
+	res = error_response(ValidationError{Schema: "university", Fields: []FieldError{{Field: "document", Message: "CNPJ must have 14 digits"}}})
+	if res.Message != `{"code":"INVALID_ARGUMENT","message":"Invalid university payload","fields":[{"field":"document","message":"CNPJ must have 14 digits"}]}` {
+		t.Fatalf("response %s", res.Message)
+	}
+}
+
+func TestInvokeErrorCodes(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+
+	tests := []struct {
+		name     string
+		caller   string
+		function string
+		args     []string
+		code     string
+	}{
+		{"unknown function", "Org1MSP:joao", "no_such_function", nil, CodeInvalidArgument},
+		{"wrong number of arguments", "Org1MSP:joao", "read", nil, CodeInvalidArgument},
+		{"malformed payload", "MECMSP:inspector", "init_university", []string{"{not json"}, CodeInvalidArgument},
+		{"unknown certificate", "Org1MSP:joao", "verify_certificate", []string{"c9"}, CodeNotFound},
+		{"certificate id taken", "Org1MSP:joao", "issue_second_copy", []string{"c1", "c1", test_cnpj}, CodeAlreadyExists},
+		{"not the dean", "Org9MSP:mallory", "suspend_university", []string{"u1", "Supervision process 123"}, CodeUnauthorized},
+		{"not allowed by the state", "MECMSP:inspector", "reactivate_university", []string{"u1", "Supervision process 123"}, CodeConflict},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).invoke(test.function, test.args...)
+			//every failure is a JSON object with a code and a message, never plain text
+			var raw map[string]interface{}
+			if err := json.Unmarshal([]byte(res.Message), &raw); err != nil || raw["code"] != test.code || len(raw["message"].(string)) == 0 {
+				t.Fatalf("error %d %s, expected %s", res.Status, res.Message, test.code)
+			}
+		})
+	}
+
+	err := fails(t, stub.as("Org1MSP", "joao").invoke("init_cert", `{"id": "c2", "university_id": "u1"}`), CodeInvalidArgument)
+	if len(err.Fields) == 0 {
+		t.Fatal("invalid payload reports no fields")
+	}
+	for _, field := range err.Fields {
+		if len(field.Field) == 0 || len(field.Message) == 0 {
+			t.Fatalf("field error %+v", field)
+		}
+	}
+}
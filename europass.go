@@ -0,0 +1,158 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jsilvaigor/AionChainCode/europass"
+)
+
+// texts of exported credentials are in portuguese, issuers are brazilian
+const europass_language = "pt"
+const europass_country = "BRA"
+
+// ========================================================
+// Build Europass Input - what the ledger knows for the Europass credential of a certificate
+//
+// The program gives the achievement, the transcript, when the certificate has one, its courses and GPA.
+// ========================================================
+func build_europass_input(stub shim.ChaincodeStubInterface, certificate Certificate) (europass.Input, University, error) {
+	var input europass.Input
+	issuer, err := get_university(stub, certificate.IssuedBy)
+	if err != nil {
+		return input, issuer, err
+	}
+	issuedAt, err := certificate_issued_at(stub, certificate)
+	if err != nil {
+		return input, issuer, err
+	}
+
+	input.Id = ledger_uri(stub, "certificate", certificate.Id)
+	input.Language = europass_language
+	input.IssuerId = ledger_uri(stub, "university", issuer.Id)
+	input.IssuerName = issuer.UniversityName
+	input.IssuerCountry = europass_country
+	input.Issued = issuedAt
+	if len(certificate.GraduateId) > 0 {
+		input.SubjectId = ledger_uri(stub, "graduate", certificate.GraduateId)
+	}
+	input.SubjectName = certificate.Name
+	input.Achievement.Description = certificate.Body
+
+	//without a program the achievement has no title nor EQF level, Build reports them
+	if len(certificate.ProgramId) > 0 {
+		program, err := get_program(stub, certificate.University.Id, certificate.ProgramId)
+		if err != nil {
+			return input, issuer, err
+		}
+		input.Achievement.Id = ledger_uri(stub, "program", certificate.University.Id+"/"+program.Id)
+		input.Achievement.Title = program.Name
+		input.Achievement.EqfLevel = europass.EqfLevel(program.Level)
+		input.Achievement.VolumeHours = program.CreditHours
+	}
+
+	transcriptKey, _ := stub.CreateCompositeKey("transcript", []string{certificate.Id})
+	transcriptAsBytes, err := stub.GetState(transcriptKey)
+	if err != nil {
+		return input, issuer, errors.New("Failed to get transcript - " + certificate.Id)
+	}
+	if transcriptAsBytes != nil {
+		transcript, err := get_transcript(stub, certificate.Id)
+		if err != nil {
+			return input, issuer, err
+		}
+		if transcript.CertificateDigest != certificate_digest(certificate) {
+			return input, issuer, new_error(CodeConflict, "Transcript of certificate '"+certificate.Id+"' no longer matches the certificate")
+		}
+		input.Achievement.Gpa = strconv.FormatFloat(transcript.Gpa, 'f', 2, 64)
+		for _, course := range transcript.Courses {
+			//failed courses are not achievements
+			grade := strconv.FormatFloat(course.Grade, 'f', 2, 64)
+			if course.Status == "failed" {
+				continue
+			} else if course.Status == "exempt" {
+				grade = "exempt"
+			}
+			input.Achievement.Parts = append(input.Achievement.Parts, europass.Part{
+				Code:        course.Code,
+				Title:       course.Name,
+				Term:        course.Term,
+				Grade:       grade,
+				CreditHours: course.CreditHours,
+			})
+		}
+	}
+	return input, issuer, nil
+}
+
+// ============================================================================================================================
+// Export Europass - a certificate as a European Digital Credential (ELM v3 JSON-LD), with the graduate portfolio access rules
+//
+// Returned unsigned, like export_vc. When a field the European Learning Model makes mandatory is missing,
+// e.g. the certificate has no program with an EQF level, nothing is exported and every missing field is reported.
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// Returns - the unsigned Europass credential JSON-LD document
+// ============================================================================================================================
+func export_europass(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_graduate_reader(stub, Graduate{Id: certificate.GraduateId}, []Certificate{certificate})
+	if err != nil {
+		return error_response(err)
+	}
+
+	input, _, err := build_europass_input(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+	credential, err := europass.Build(input)
+	if missing, ok := err.(europass.MissingFields); ok {
+		var field_errors []FieldError
+		for _, field := range missing.Fields {
+			field_errors = append(field_errors, FieldError{Field: field, Message: "is mandatory in the European Learning Model"})
+		}
+		return error_response(ValidationError{Schema: "europass", Fields: field_errors})
+	} else if err != nil {
+		return error_response(err)
+	}
+	credential.CredentialStatus = certificate_status(stub, certificate.Id)
+
+	credentialAsBytes, _ := json.Marshal(credential) //convert to array of bytes
+	return shim.Success(credentialAsBytes)
+}
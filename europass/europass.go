@@ -0,0 +1,207 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package europass builds European Learning Model (ELM v3) credentials for Europass.
+//
+// Credentials are European Digital Credentials in JSON-LD, which are W3C Verifiable Credentials, so they are
+// built and signed with package vc. Documents missing a field the model makes mandatory are never built,
+// Build reports every missing field instead.
+package europass
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+const (
+	ContextEdc     = "http://data.europa.eu/snb/model/context/edc-ap"
+	TypeCredential = "EuropeanDigitalCredential"
+	SchemaId       = "http://data.europa.eu/snb/model/ap/edc-generic-full"
+	CountryBase    = "http://publications.europa.eu/resource/authority/country/"
+	EqfBase        = "http://data.europa.eu/snb/eqf/"
+)
+
+// ----- Input ----- //
+// what the ledger knows about a credential
+type Input struct {
+	Id            string //credential id
+	Language      string //language of the texts, e.g. "pt"
+	IssuerId      string
+	IssuerName    string
+	IssuerCountry string //ISO 3166-1 alpha-3, e.g. "BRA"
+	Issued        time.Time
+	SubjectId     string //optional
+	SubjectName   string
+	Achievement   Achievement
+}
+
+// ----- Achievement ----- //
+type Achievement struct {
+	Id          string
+	Title       string //e.g. the program name
+	Description string //e.g. the certificate body
+	EqfLevel    int    //1-8, see EqfLevel
+	VolumeHours int    //credit hours of the program
+	Gpa         string //overall grade, empty without a transcript
+	Parts       []Part //courses of the transcript, optional
+}
+
+// ----- Part ----- //
+type Part struct {
+	Code        string
+	Title       string
+	Term        string
+	Grade       string
+	CreditHours int
+}
+
+// ----- Missing Fields ----- //
+type MissingFields struct {
+	Fields []string //ELM paths of the missing mandatory fields
+}
+
+func (e MissingFields) Error() string {
+	return "europass: missing mandatory " + strings.Join(e.Fields, ", ")
+}
+
+// EQF level of each program level of the catalog
+var eqf_levels = map[string]int{
+	"technologist":   5,
+	"bachelor":       6,
+	"licentiate":     6,
+	"specialization": 7,
+	"master":         7,
+	"doctorate":      8,
+}
+
+// ========================================================
+// EQF Level - the European Qualifications Framework level of a program level, 0 when it has none
+// ========================================================
+func EqfLevel(level string) int {
+	return eqf_levels[level]
+}
+
+// ========================================================
+// Missing - ELM paths of the mandatory fields the input leaves empty
+// ========================================================
+func Missing(in Input) []string {
+	var missing []string
+	required := []struct {
+		path    string
+		present bool
+	}{
+		{"id", len(in.Id) > 0},
+		{"issuer.id", len(in.IssuerId) > 0},
+		{"issuer.name", len(in.IssuerName) > 0},
+		{"issuer.location.countryCode", len(in.IssuerCountry) == 3},
+		{"issuanceDate", !in.Issued.IsZero()},
+		{"credentialSubject.fullName", len(in.SubjectName) > 0},
+		{"credentialSubject.hasClaim.id", len(in.Achievement.Id) > 0},
+		{"credentialSubject.hasClaim.title", len(in.Achievement.Title) > 0},
+		{"credentialSubject.hasClaim.specifiedBy.eqfLevel", in.Achievement.EqfLevel >= 1 && in.Achievement.EqfLevel <= 8},
+		{"language", len(in.Language) > 0},
+	}
+	for _, field := range required {
+		if !field.present {
+			missing = append(missing, field.path)
+		}
+	}
+	for i, part := range in.Achievement.Parts {
+		if len(part.Title) == 0 {
+			missing = append(missing, "credentialSubject.hasClaim.hasPart["+strconv.Itoa(i)+"].title")
+		}
+	}
+	return missing
+}
+
+// ========================================================
+// Build - the unsigned European Digital Credential, or MissingFields listing every gap
+// ========================================================
+func Build(in Input) (vc.Credential, error) {
+	if missing := Missing(in); len(missing) > 0 {
+		return vc.Credential{}, MissingFields{Fields: missing}
+	}
+	text := func(str string) map[string]interface{} {
+		return map[string]interface{}{in.Language: str}
+	}
+
+	specification := map[string]interface{}{
+		"type":     "LearningAchievementSpecification",
+		"title":    text(in.Achievement.Title),
+		"eqfLevel": map[string]interface{}{"id": EqfBase + strconv.Itoa(in.Achievement.EqfLevel), "type": "Concept"},
+	}
+	if in.Achievement.VolumeHours > 0 {
+		specification["volumeOfLearning"] = "PT" + strconv.Itoa(in.Achievement.VolumeHours) + "H"
+	}
+	claim := map[string]interface{}{
+		"id":          in.Achievement.Id,
+		"type":        "LearningAchievement",
+		"title":       text(in.Achievement.Title),
+		"specifiedBy": specification,
+	}
+	if len(in.Achievement.Description) > 0 {
+		claim["description"] = text(in.Achievement.Description)
+	}
+	if len(in.Achievement.Gpa) > 0 {
+		claim["wasDerivedFrom"] = []interface{}{map[string]interface{}{
+			"type":  "LearningAssessment",
+			"title": text("Overall grade"),
+			"grade": map[string]interface{}{"type": "Note", "noteLiteral": text(in.Achievement.Gpa)},
+		}}
+	}
+	var parts []interface{}
+	for _, part := range in.Achievement.Parts {
+		parts = append(parts, map[string]interface{}{
+			"id":    in.Achievement.Id + "#" + part.Code + "-" + part.Term,
+			"type":  "LearningAchievement",
+			"title": text(part.Title),
+			"specifiedBy": map[string]interface{}{
+				"type":             "LearningAchievementSpecification",
+				"title":            text(part.Title),
+				"volumeOfLearning": "PT" + strconv.Itoa(part.CreditHours) + "H",
+			},
+			"wasDerivedFrom": []interface{}{map[string]interface{}{
+				"type":  "LearningAssessment",
+				"title": text(part.Term),
+				"grade": map[string]interface{}{"type": "Note", "noteLiteral": text(part.Grade)},
+			}},
+		})
+	}
+	if len(parts) > 0 {
+		claim["hasPart"] = parts
+	}
+
+	subject := map[string]interface{}{
+		"type":     "Person",
+		"fullName": text(in.SubjectName),
+		"hasClaim": []interface{}{claim},
+	}
+	if len(in.SubjectId) > 0 {
+		subject["id"] = in.SubjectId
+	}
+
+	credential := vc.New(in.Id, []string{TypeCredential}, vc.Issuer{Id: in.IssuerId, Type: []string{"Organisation"}, Name: in.IssuerName}, in.Issued, subject)
+	credential.Context = append(credential.Context, ContextEdc)
+	credential.CredentialSchema = []vc.Schema{{Id: SchemaId, Type: "ShaclValidator2017"}}
+	credential.Issuer.Location = &vc.Location{CountryCode: CountryBase + in.IssuerCountry}
+	return credential, nil
+}
@@ -0,0 +1,113 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package europass
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func test_input() Input {
+	return Input{Id: "urn:aion:ch:certificate:c1", Language: "pt", IssuerId: "urn:aion:ch:university:u1", IssuerName: "Universidade Um",
+		IssuerCountry: "BRA", Issued: time.Date(2017, 8, 4, 12, 0, 0, 0, time.UTC), SubjectName: "Maria",
+		Achievement: Achievement{Id: "urn:aion:ch:program:u1/p1", Title: "Computer Science", EqfLevel: 6, VolumeHours: 3200}}
+}
+
+func TestEqfLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		eqf   int
+	}{
+		{"technologist", 5},
+		{"bachelor", 6},
+		{"licentiate", 6},
+		{"specialization", 7},
+		{"master", 7},
+		{"doctorate", 8},
+		{"workshop", 0},
+		{"", 0},
+	}
+	for _, test := range tests {
+		if eqf := EqfLevel(test.level); eqf != test.eqf {
+			t.Errorf("%q - EQF level %d, expected %d", test.level, eqf, test.eqf)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(in *Input)
+		missing []string
+	}{
+		{"complete", func(in *Input) {}, nil},
+		{"no id", func(in *Input) { in.Id = "" }, []string{"id"}},
+		{"country is no alpha-3 code", func(in *Input) { in.IssuerCountry = "BR" }, []string{"issuer.location.countryCode"}},
+		{"no issuance date", func(in *Input) { in.Issued = time.Time{} }, []string{"issuanceDate"}},
+		{"no language", func(in *Input) { in.Language = "" }, []string{"language"}},
+		{"EQF level out of range", func(in *Input) { in.Achievement.EqfLevel = 9 }, []string{"credentialSubject.hasClaim.specifiedBy.eqfLevel"}},
+		{"no program", func(in *Input) { in.Achievement = Achievement{} }, []string{"credentialSubject.hasClaim.id",
+			"credentialSubject.hasClaim.title", "credentialSubject.hasClaim.specifiedBy.eqfLevel"}},
+		{"course without a title", func(in *Input) { in.Achievement.Parts = []Part{{Code: "MAT101", Title: "Calculus"}, {Code: "MAT102"}} },
+			[]string{"credentialSubject.hasClaim.hasPart[1].title"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := test_input()
+			test.change(&in)
+			if missing := Missing(in); !reflect.DeepEqual(missing, test.missing) {
+				t.Fatalf("missing %v, expected %v", missing, test.missing)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	in := test_input()
+	in.Achievement.Gpa = "8.00"
+	in.Achievement.Parts = []Part{{Code: "MAT101", Title: "Calculus", Term: "2013/1", Grade: "8.00", CreditHours: 60}}
+	credential, err := Build(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Id != in.Id || credential.Issuer.Location == nil || credential.Issuer.Location.CountryCode != CountryBase+"BRA" {
+		t.Fatalf("credential %+v", credential)
+	}
+	if len(credential.CredentialSchema) != 1 || credential.CredentialSchema[0].Id != SchemaId {
+		t.Fatalf("schema %+v", credential.CredentialSchema)
+	}
+	claim := credential.CredentialSubject["hasClaim"].([]interface{})[0].(map[string]interface{})
+	eqf := claim["specifiedBy"].(map[string]interface{})["eqfLevel"].(map[string]interface{})
+	if eqf["id"] != EqfBase+"6" {
+		t.Fatalf("EQF level %v", eqf["id"])
+	}
+	if parts := claim["hasPart"].([]interface{}); len(parts) != 1 {
+		t.Fatalf("parts %v", parts)
+	}
+
+	in.Achievement.Title = ""
+	in.IssuerName = ""
+	_, err = Build(in)
+	missing, ok := err.(MissingFields)
+	if !ok || !reflect.DeepEqual(missing.Fields, []string{"issuer.name", "credentialSubject.hasClaim.title"}) {
+		t.Fatalf("error %v", err)
+	}
+}
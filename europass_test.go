@@ -0,0 +1,112 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jsilvaigor/AionChainCode/europass"
+)
+
+func TestExportEuropass(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_transcript", `{"certificate_id": "c1", "courses": `+test_courses+`}`))
+
+	readers := []struct {
+		name      string
+		caller    string
+		transient map[string]string
+		code      string
+	}{
+		{"stranger", "Org9MSP:mallory", nil, CodeUnauthorized},
+		{"stranger with another document", "Org9MSP:mallory", map[string]string{"cpf": "111.444.777-35"}, CodeUnauthorized},
+		{"graduate proving the document", "Org9MSP:maria", map[string]string{"cpf": test_cpf}, ""},
+		{"dean of the issuer", "Org1MSP:joao", nil, ""},
+		{"accreditor of the issuer", "MECMSP:inspector", nil, ""},
+	}
+	for _, test := range readers {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).with(test.transient).invoke("export_europass", "c1")
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			var credential struct {
+				Type              []string `json:"type"`
+				CredentialSubject struct {
+					FullName map[string]string `json:"fullName"`
+					HasClaim []struct {
+						SpecifiedBy struct {
+							EqfLevel struct {
+								Id string `json:"id"`
+							} `json:"eqfLevel"`
+						} `json:"specifiedBy"`
+						HasPart []interface{} `json:"hasPart"`
+					} `json:"hasClaim"`
+				} `json:"credentialSubject"`
+			}
+			json.Unmarshal(ok(t, res), &credential)
+			if !contains(credential.Type, europass.TypeCredential) || credential.CredentialSubject.FullName["pt"] != "Maria" {
+				t.Fatalf("credential %+v", credential)
+			}
+			claims := credential.CredentialSubject.HasClaim
+			if len(claims) != 1 || claims[0].SpecifiedBy.EqfLevel.Id != europass.EqfBase+"6" || len(claims[0].HasPart) != 1 {
+				t.Fatalf("claims %+v", claims)
+			}
+		})
+	}
+
+	fails(t, stub.as("Org1MSP", "joao").invoke("export_europass"), CodeInvalidArgument)
+	fails(t, stub.invoke("export_europass", "c9"), CodeNotFound)
+
+	//a certificate changed behind the chaincode no longer matches its transcript
+	certificateAsBytes, _ := stub.GetState("c1")
+	var fields map[string]interface{}
+	json.Unmarshal(certificateAsBytes, &fields)
+	fields["name"] = "Mario"
+	stub.put("c1", fields)
+	fails(t, stub.invoke("export_europass", "c1"), CodeConflict)
+}
+
+func TestExportEuropassMissingFields(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+
+	//a certificate without a program has no title nor EQF level
+	certificateAsBytes, _ := stub.GetState("c1")
+	var fields map[string]interface{}
+	json.Unmarshal(certificateAsBytes, &fields)
+	fields["programId"] = ""
+	stub.put("c1", fields)
+
+	err := fails(t, stub.as("Org1MSP", "joao").invoke("export_europass", "c1"), CodeInvalidArgument)
+	missing := map[string]bool{}
+	for _, field := range err.Fields {
+		missing[field.Field] = true
+	}
+	for _, field := range []string{"credentialSubject.hasClaim.id", "credentialSubject.hasClaim.title", "credentialSubject.hasClaim.specifiedBy.eqfLevel"} {
+		if !missing[field] {
+			t.Errorf("%s not reported in %+v", field, err.Fields)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Proposal Payload ----- //
+type ProposalPayload struct {
+	Id      string          `json:"id"`
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+var proposal_schema = PayloadSchema{
+	Name: "proposal",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of proposal"},
+		{Name: "action", Type: "string", Required: true, MaxLength: 32, Description: "\"admit_university\", \"suspend_university\" or \"change_config\""},
+		{Name: "payload", Type: "object", Required: true, Description: "Payload of the action, as the matching function takes it"},
+	},
+}
+
+// ----- Suspension Payload ----- //
+type SuspensionPayload struct {
+	UniversityId string `json:"university_id"`
+	Reason       string `json:"reason"`
+}
+
+var suspension_schema = PayloadSchema{
+	Name: "suspension",
+	Fields: []FieldSpec{
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university to suspend"},
+		{Name: "reason", Type: "string", Required: true, MaxLength: 512, Description: "Why it is suspended"},
+	},
+}
+
+// ========================================================
+// Get Proposal - get a proposal asset from ledger
+// ========================================================
+func get_proposal(stub shim.ChaincodeStubInterface, id string) (Proposal, error) {
+	var proposal Proposal
+	proposalKey, _ := stub.CreateCompositeKey("proposal", []string{id})
+	proposalAsBytes, err := stub.GetState(proposalKey)
+	if err != nil {
+		return proposal, errors.New("Failed to get proposal - " + id)
+	}
+	if proposalAsBytes == nil {
+		return proposal, new_error(CodeNotFound, "Proposal does not exist - "+id)
+	}
+	err = json.Unmarshal(proposalAsBytes, &proposal) //un stringify it aka JSON.parse()
+	if err != nil {
+		return proposal, errors.New("Proposal is not readable - " + id)
+	}
+	return proposal, nil
+}
+
+// ========================================================
+// Put Proposal - store a proposal asset
+// ========================================================
+func put_proposal(stub shim.ChaincodeStubInterface, proposal Proposal) error {
+	proposalKey, _ := stub.CreateCompositeKey("proposal", []string{proposal.Id})
+	return put_asset(stub, proposalKey, &proposal)
+}
+
+// ========================================================
+// Require Member - fail unless the transaction creator belongs to a consortium member, returns the identity
+// ========================================================
+func require_member(stub shim.ChaincodeStubInterface, members []string) (Identity, error) {
+	identity, err := get_creator(stub)
+	if err != nil {
+		return identity, err
+	}
+	for _, member := range members {
+		if member == identity.MspId {
+			return identity, nil
+		}
+	}
+	return identity, new_error(CodeUnauthorized, "Identity '"+identity.Id+"' is not a consortium member")
+}
+
+// ========================================================
+// Check Action - validate the payload of a governance action without executing it
+// ========================================================
+func check_action(stub shim.ChaincodeStubInterface, action string, payload json.RawMessage) error {
+	args := []string{string(payload)}
+	switch action {
+	case "admit_university":
+		var university UniversityPayload
+		err := parse_payload(args, university_schema, &university)
+		if err != nil {
+			return err
+		}
+		_, err = check_university_cnpj(stub, university.Document)
+		return err
+	case "suspend_university":
+		var suspension SuspensionPayload
+		return parse_payload(args, suspension_schema, &suspension)
+	case "change_config":
+		_, err := parse_config(stub, args)
+		return err
+	}
+	return new_error(CodeInvalidArgument, "Unknown governance action - '"+action+"'")
+}
+
+// ========================================================
+// Execute Action - carry out an approved governance action
+// ========================================================
+func execute_action(stub shim.ChaincodeStubInterface, action string, payload json.RawMessage) error {
+	args := []string{string(payload)}
+	switch action {
+	case "admit_university":
+		var university UniversityPayload
+		err := parse_payload(args, university_schema, &university)
+		if err != nil {
+			return err
+		}
+		return create_university(stub, university)
+	case "suspend_university":
+		var suspension SuspensionPayload
+		err := parse_payload(args, suspension_schema, &suspension)
+		if err != nil {
+			return err
+		}
+		university, err := get_university(stub, suspension.UniversityId)
+		if err != nil {
+			return err
+		}
+		if university.Status != "active" {
+			return new_error(CodeConflict, "University '"+university.Id+"' is "+university.Status)
+		}
+		return set_university_status(stub, university, "suspended", suspension.Reason)
+	case "change_config":
+		config, err := parse_config(stub, args)
+		if err != nil {
+			return err
+		}
+		return put_config(stub, config)
+	}
+	return new_error(CodeInvalidArgument, "Unknown governance action - '"+action+"'")
+}
+
+// ========================================================
+// Tally Proposal - update the status of an open proposal from its votes
+// ========================================================
+func tally_proposal(proposal *Proposal) {
+	var approvals, rejections int
+	for _, vote := range proposal.Votes {
+		if vote.Approve {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+
+	needed := proposal.QuorumPercent * len(proposal.Members)
+	if approvals*100 >= needed {
+		proposal.Status = "approved"
+	} else if (len(proposal.Members)-rejections)*100 < needed {
+		proposal.Status = "rejected" //not enough members left to approve it
+	}
+}
+
+// ============================================================================================================================
+// Propose - open a governance proposal, consortium members only
+//
+// The quorum, members and deadline are fixed from the config when the proposal opens.
+//
+// Inputs - JSON payload validated against proposal_schema
+//
+//	{
+//		"id": "p123",
+//		"action": "admit_university",
+//		"payload": {"id": "u123", "dean": "joao", "name": "uniuni", "document": "11.222.333/0001-81"}
+//	}
+//
+// ============================================================================================================================
+func propose(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting propose")
+
+	config, err := get_config(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	identity, err := require_member(stub, config.Governance.Members)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//input sanitation
+	var payload ProposalPayload
+	err = parse_payload(args, proposal_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	err = check_action(stub, payload.Action, payload.Payload)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check if proposal already exists
+	_, err = get_proposal(stub, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This proposal already exists - "+payload.Id))
+	}
+
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	var proposal Proposal
+	proposal.ObjectType = "proposal"
+	proposal.SchemaVersion = ProposalSchemaVersion
+	proposal.Id = payload.Id
+	proposal.Action = payload.Action
+	proposal.Payload = payload.Payload
+	proposal.Proposer = identity.Id
+	proposal.Status = "open"
+	proposal.Members = config.Governance.Members
+	proposal.QuorumPercent = config.Governance.QuorumPercent
+	proposal.CreatedAt = now.Format(time.RFC3339)
+	proposal.Deadline = now.Add(time.Duration(config.Governance.VotingPeriodHours) * time.Hour).Format(time.RFC3339)
+
+	err = put_proposal(stub, proposal)
+	if err != nil {
+		fmt.Println("Could not store proposal")
+		return error_response(err)
+	}
+
+	fmt.Println("- end propose")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Vote - cast the vote of a consortium member on an open proposal
+//
+// One vote per member MSP. The vote that approves the proposal also executes its action, so that vote
+// fails if the action can no longer be carried out.
+//
+// Inputs - Array of Strings
+//
+//	0           | 1
+//	proposal_id | "yes"/"no"
+//	"p123"      | "yes"
+//
+// ============================================================================================================================
+func vote(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting vote")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	if args[1] != "yes" && args[1] != "no" {
+		return error_response(new_error(CodeInvalidArgument, "Vote must be 'yes' or 'no' - got '"+args[1]+"'"))
+	}
+
+	proposal, err := get_proposal(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	if proposal.Status != "open" {
+		return error_response(new_error(CodeConflict, "Proposal '"+proposal.Id+"' is "+proposal.Status))
+	}
+
+	// votes are bound to the members fixed when the proposal opened
+	identity, err := require_member(stub, proposal.Members)
+	if err != nil {
+		return error_response(err)
+	}
+	for _, cast := range proposal.Votes {
+		if cast.Member == identity.MspId {
+			return error_response(new_error(CodeConflict, "Member '"+identity.MspId+"' already voted on proposal '"+proposal.Id+"'"))
+		}
+	}
+
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	deadline, _ := time.Parse(time.RFC3339, proposal.Deadline)
+	if now.After(deadline) {
+		return error_response(new_error(CodeConflict, "Voting on proposal '"+proposal.Id+"' closed at "+proposal.Deadline+", close it instead"))
+	}
+
+	proposal.Votes = append(proposal.Votes, Vote{Member: identity.MspId, Identity: identity.Id, Approve: args[1] == "yes", TxId: stub.GetTxID()})
+	tally_proposal(&proposal)
+
+	if proposal.Status == "approved" {
+		err = execute_action(stub, proposal.Action, proposal.Payload)
+		if err != nil {
+			return error_response(wrap_error("Proposal '"+proposal.Id+"' is approved but its action failed", err))
+		}
+		proposal.Status = "executed"
+		proposal.ExecutedTxId = stub.GetTxID()
+	}
+
+	err = put_proposal(stub, proposal)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end vote, proposal is " + proposal.Status)
+	proposalAsBytes, _ := json.Marshal(proposal) //convert to array of bytes
+	return shim.Success(proposalAsBytes)
+}
+
+// ============================================================================================================================
+// Close Proposal - mark an open proposal whose deadline passed as expired
+//
+// Inputs - Array of strings
+//
+//	0
+//	proposal_id
+//	"p123"
+//
+// ============================================================================================================================
+func close_proposal(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting close_proposal")
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	proposal, err := get_proposal(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	if proposal.Status != "open" {
+		return error_response(new_error(CodeConflict, "Proposal '"+proposal.Id+"' is "+proposal.Status))
+	}
+
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	deadline, _ := time.Parse(time.RFC3339, proposal.Deadline)
+	if !now.After(deadline) {
+		return error_response(new_error(CodeConflict, "Proposal '"+proposal.Id+"' is open until "+proposal.Deadline))
+	}
+
+	proposal.Status = "expired"
+	err = put_proposal(stub, proposal)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end close_proposal")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Proposals - list proposals, optionally only those with a given status
+//
+// Inputs - Array of strings
+//
+//	0
+//	status (optional)
+//	"open"
+//
+// ============================================================================================================================
+func read_proposals(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var proposals []Proposal
+
+	if len(args) > 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting optional status"))
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("proposal", []string{})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var proposal Proposal
+		json.Unmarshal(record.Value, &proposal) //un stringify it aka JSON.parse()
+		if len(args) == 0 || proposal.Status == args[0] {
+			proposals = append(proposals, proposal)
+		}
+	}
+
+	proposalsAsBytes, _ := json.Marshal(proposals) //convert to array of bytes
+	return shim.Success(proposalsAsBytes)
+}
@@ -0,0 +1,148 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTallyProposal(t *testing.T) {
+	tests := []struct {
+		members int
+		quorum  int
+		yes     int
+		no      int
+		status  string
+	}{
+		{1, 50, 1, 0, "approved"},
+		{3, 50, 1, 0, "open"},
+		{3, 50, 2, 0, "approved"},
+		{3, 50, 1, 1, "open"},
+		{3, 50, 0, 2, "rejected"},
+		{3, 67, 2, 0, "open"},
+		{3, 67, 0, 1, "rejected"},
+		{3, 100, 2, 1, "rejected"},
+		{4, 50, 2, 0, "approved"},
+		{4, 50, 0, 2, "open"},
+		{4, 50, 0, 3, "rejected"},
+	}
+	for _, test := range tests {
+		proposal := Proposal{Status: "open", QuorumPercent: test.quorum}
+		for i := 0; i < test.members; i++ {
+			proposal.Members = append(proposal.Members, "Org"+string(rune('1'+i))+"MSP")
+		}
+		for i := 0; i < test.yes+test.no; i++ {
+			proposal.Votes = append(proposal.Votes, Vote{Member: proposal.Members[i], Approve: i < test.yes})
+		}
+		tally_proposal(&proposal)
+		if proposal.Status != test.status {
+			t.Errorf("%d members, %d%% quorum, %d yes and %d no - %s, expected %s", test.members, test.quorum, test.yes, test.no, proposal.Status, test.status)
+		}
+	}
+}
+
+// new_governed_ledger - a test ledger where three members admit universities by majority
+func new_governed_ledger(t *testing.T) *test_stub {
+	stub := new_test_stub()
+	ok(t, stub.as("Org1MSP", "admin").init(`{"regulatorMsp": "MECMSP", "admins": ["`+test_admin+`"], "features": {"governance": true},
+		"governance": {"members": ["Org1MSP", "Org2MSP", "Org3MSP"], "quorumPercent": 50, "votingPeriodHours": 24}}`))
+	ok(t, stub.invoke("init_accreditor", `{"id": "a1", "name": "MEC", "mspId": "MECMSP"}`))
+	return stub
+}
+
+const test_admission = `{"id": "p1", "action": "admit_university", "payload": {"id": "u1", "dean": "Joao", "name": "Universidade Um", "document": "` + test_cnpj + `"}}`
+
+func TestAdmitUniversityByVote(t *testing.T) {
+	stub := new_governed_ledger(t)
+	fails(t, stub.as("MECMSP", "inspector").invoke("init_university", `{"id": "u1", "dean": "Joao", "name": "Universidade Um", "document": "`+test_cnpj+`"}`), CodeConflict)
+	fails(t, stub.as("Org9MSP", "mallory").invoke("propose", test_admission), CodeUnauthorized)
+	fails(t, stub.as("Org1MSP", "member").invoke("propose", `{"id": "p1", "action": "admit_university", "payload": {"id": "u1", "dean": "Joao", "name": "Uni", "document": "11.222.333/0001-00"}}`), CodeInvalidArgument)
+	ok(t, stub.as("Org1MSP", "member").invoke("propose", test_admission))
+	fails(t, stub.as("Org2MSP", "member").invoke("propose", test_admission), CodeAlreadyExists)
+
+	steps := []struct {
+		name   string
+		caller string
+		vote   string
+		code   string
+		status string
+	}{
+		{"not a member", "Org9MSP", "yes", CodeUnauthorized, ""},
+		{"not a vote", "Org1MSP", "maybe", CodeInvalidArgument, ""},
+		{"first approval", "Org1MSP", "yes", "", "open"},
+		{"second vote of a member", "Org1MSP", "no", CodeConflict, ""},
+		{"approval reaching the quorum", "Org2MSP", "yes", "", "executed"},
+		{"vote after execution", "Org3MSP", "no", CodeConflict, ""},
+	}
+	for _, step := range steps {
+		res := stub.as(step.caller, "member").invoke("vote", "p1", step.vote)
+		if step.code != "" {
+			fails(t, res, step.code)
+			continue
+		}
+		var proposal Proposal
+		json.Unmarshal(ok(t, res), &proposal)
+		if proposal.Status != step.status {
+			t.Fatalf("%s - proposal is %s, expected %s", step.name, proposal.Status, step.status)
+		}
+	}
+
+	university, err := get_university(stub, "u1")
+	if err != nil || university.Status != "active" {
+		t.Fatalf("university was not admitted - %+v %v", university, err)
+	}
+}
+
+func TestProposalExpires(t *testing.T) {
+	stub := new_governed_ledger(t)
+	ok(t, stub.as("Org1MSP", "member").invoke("propose", test_admission))
+	fails(t, stub.as("Org9MSP", "anyone").invoke("close_proposal", "p1"), CodeConflict)
+
+	stub.clock = stub.clock.Add(25 * time.Hour)
+	fails(t, stub.as("Org1MSP", "member").invoke("vote", "p1", "yes"), CodeConflict)
+	ok(t, stub.as("Org9MSP", "anyone").invoke("close_proposal", "p1"))
+
+	proposal, err := get_proposal(stub, "p1")
+	if err != nil || proposal.Status != "expired" {
+		t.Fatalf("proposal %+v %v", proposal, err)
+	}
+	if _, err := get_university(stub, "u1"); err == nil {
+		t.Fatal("an expired proposal admitted the university")
+	}
+}
+
+func TestChangeConfigByVote(t *testing.T) {
+	stub := new_governed_ledger(t)
+	ungoverned := `{"regulatorMsp": "MECMSP", "admins": ["` + test_admin + `"]}`
+	fails(t, stub.as("Org1MSP", "admin").invoke("update_config", ungoverned), CodeConflict)
+
+	ok(t, stub.as("Org1MSP", "member").invoke("propose", `{"id": "p1", "action": "change_config", "payload": `+ungoverned+`}`))
+	ok(t, stub.as("Org1MSP", "member").invoke("vote", "p1", "yes"))
+	ok(t, stub.as("Org2MSP", "member").invoke("vote", "p1", "yes"))
+	config, err := get_config(stub)
+	if err != nil || config.Features["governance"] {
+		t.Fatalf("config %+v %v", config, err)
+	}
+
+	//without governance the admins manage the config again
+	ok(t, stub.as("Org1MSP", "admin").invoke("update_config", ungoverned))
+}
@@ -0,0 +1,327 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Graduate Registry - every certificate links to a pseudonymous graduate
+//
+// The graduate id is HMAC-SHA256 of the canonical CPF under a consortium wide key. The key never touches the ledger,
+// it is passed in the transient map as "graduate_key" and checked against the digest in the chaincode config, so
+// the same person gets the same id whichever university issues. Only admins hold the key, they link certificates
+// to their graduate with link_certificate_graduate. Graduates prove themselves with their document, matched
+// against the restricted document records of their certificates.
+//
+// Restricted records are plain world state, replicated to every peer of the channel like any other key. They are
+// not a private data collection, the chaincode only refuses to serve them through read and getUniversityHistory.
+// ============================================================================================================================
+
+// records holding personal data, never returned by the generic read or the history query
+var restricted_object_types = map[string]bool{"graduate": true, "transcript": true, "credit_recognition": true, "credit_consent": true, "certificate_document": true}
+
+// ========================================================
+// Restricted Object Type - the restricted object type a key belongs to, empty for every other key
+//
+// Compares the composite key prefix directly, SplitCompositeKey assumes a composite key and panics on plain keys.
+// ========================================================
+func restricted_object_type(key string) string {
+	for objectType := range restricted_object_types {
+		if strings.HasPrefix(key, "\x00"+objectType+"\x00") {
+			return objectType
+		}
+	}
+	return ""
+}
+
+// ========================================================
+// Get Graduate Key - read the HMAC key from the transient map and check it against the configured digest
+// ========================================================
+func get_graduate_key(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	config, err := get_config(stub)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.GraduateKeyDigest) == 0 {
+		return nil, new_error(CodeConflict, "The graduate key is not configured, set graduateKeyDigest in the chaincode config")
+	}
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, errors.New("Failed to get transient data - " + err.Error())
+	}
+	key := transient["graduate_key"]
+	if len(key) == 0 {
+		return nil, new_error(CodeInvalidArgument, "The graduate key must be sent in the transient map as \"graduate_key\"")
+	}
+	digest := sha256.Sum256(key)
+	if hex.EncodeToString(digest[:]) != config.GraduateKeyDigest {
+		return nil, new_error(CodeUnauthorized, "The graduate key does not match the configured digest")
+	}
+	return key, nil
+}
+
+// ========================================================
+// Derive Graduate Id - HMAC-SHA256 of the canonical document, hex encoded
+//
+// A CPF is keyed by its digits, so certificates stored before CPFs were validated still find their graduate.
+// Passports are prefixed so they can never collide with a CPF.
+// ========================================================
+func derive_graduate_id(key []byte, document_type string, document string) string {
+	subject := only_digits(document)
+	if document_type == "passport" {
+		subject = "passport:" + document
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(subject))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ========================================================
+// Get Graduate - get a graduate from ledger
+// ========================================================
+func get_graduate(stub shim.ChaincodeStubInterface, graduate_id string) (Graduate, error) {
+	var graduate Graduate
+	graduateKey, _ := stub.CreateCompositeKey("graduate", []string{graduate_id})
+	graduateAsBytes, err := stub.GetState(graduateKey)
+	if err != nil {
+		return graduate, errors.New("Failed to get graduate - " + graduate_id)
+	}
+	if graduateAsBytes == nil {
+		return graduate, new_error(CodeNotFound, "Graduate does not exist - "+graduate_id)
+	}
+	err = json.Unmarshal(graduateAsBytes, &graduate) //un stringify it aka JSON.parse()
+	if err != nil {
+		return graduate, errors.New("Graduate is not readable - " + graduate_id)
+	}
+	return graduate, nil
+}
+
+// ========================================================
+// Put Graduate - store a graduate
+// ========================================================
+func put_graduate(stub shim.ChaincodeStubInterface, graduate Graduate) error {
+	graduateKey, _ := stub.CreateCompositeKey("graduate", []string{graduate.Id})
+	return put_asset(stub, graduateKey, &graduate)
+}
+
+// ========================================================
+// Link Graduate - add a certificate to the graduate, creating the graduate on their first certificate
+// ========================================================
+func link_graduate(stub shim.ChaincodeStubInterface, graduate_id string, certificate_id string) error {
+	graduate, err := get_graduate(stub, graduate_id)
+	if err != nil && as_chaincode_error(err).Code != CodeNotFound {
+		return err
+	}
+	if err != nil {
+		graduate = Graduate{ObjectType: "graduate", SchemaVersion: GraduateSchemaVersion, Id: graduate_id}
+	}
+	if contains(graduate.Certificates, certificate_id) {
+		return nil
+	}
+	graduate.Certificates = append(graduate.Certificates, certificate_id)
+	return put_graduate(stub, graduate)
+}
+
+// ========================================================
+// Require Graduate Reader - who may see everything a graduate holds
+//
+// Admins, accreditors, the dean or a registrar of a university that issued one of the certificates, and a
+// caller proving the document of one of the certificates by sending "cpf", or "passport" for foreign graduates,
+// in the transient map.
+// ========================================================
+func require_graduate_reader(stub shim.ChaincodeStubInterface, graduate Graduate, certificates []Certificate) error {
+	if require_admin(stub) == nil {
+		return nil
+	}
+	if _, err := get_creator_accreditor(stub); err == nil {
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, certificate := range certificates {
+		if checked[certificate.IssuedBy] {
+			continue
+		}
+		checked[certificate.IssuedBy] = true
+		university, err := get_university(stub, certificate.IssuedBy)
+		if err == nil && require_staff(stub, university) == nil {
+			return nil
+		}
+	}
+
+	var certificate_ids []string
+	for _, certificate := range certificates {
+		certificate_ids = append(certificate_ids, certificate.Id)
+	}
+	if proves_graduate(stub, certificate_ids) == nil {
+		return nil
+	}
+	return new_error(CodeUnauthorized, "Not allowed to read the credentials of graduate '"+graduate.Id+"'")
+}
+
+// ========================================================
+// Proves Graduate - the caller sent the document, "cpf" or "passport", of one of the certificates in the transient map
+// ========================================================
+func proves_graduate(stub shim.ChaincodeStubInterface, certificate_ids []string) error {
+	transient, err := stub.GetTransient()
+	if err != nil || (len(transient["cpf"]) == 0 && len(transient["passport"]) == 0) {
+		return new_error(CodeUnauthorized, "No graduate document in the transient map")
+	}
+	document_type := "cpf"
+	if len(transient["cpf"]) == 0 {
+		document_type = "passport"
+	}
+	document, err := canonical_document(document_type, string(transient[document_type]))
+	if err != nil {
+		return err
+	}
+	for _, certificate_id := range certificate_ids {
+		stored, err := get_certificate_document(stub, certificate_id)
+		if err != nil {
+			continue
+		}
+		if stored.DocumentType == document_type && hmac.Equal([]byte(stored.Document), []byte(document)) {
+			return nil
+		}
+	}
+	return new_error(CodeUnauthorized, "The document does not belong to the graduate of these certificates")
+}
+
+// ============================================================================================================================
+// Link Certificate Graduate - link a certificate to its graduate, admin only
+//
+// Issuers never hold the graduate key, so every certificate is linked here once issued.
+//
+// Transient - "graduate_key"
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// ============================================================================================================================
+func link_certificate_graduate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting link_certificate_graduate")
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	err = require_admin(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	key, err := get_graduate_key(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	graduate_id := derive_graduate_id(key, certificate.DocumentType, certificate.Document)
+	if len(certificate.GraduateId) > 0 && certificate.GraduateId != graduate_id {
+		return error_response(new_error(CodeConflict, "Certificate '"+certificate.Id+"' is linked to another graduate"))
+	}
+
+	certificate.GraduateId = graduate_id
+	certificate.SchemaVersion = CertificateSchemaVersion
+	err = put_certificate(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+	err = link_graduate(stub, graduate_id, certificate.Id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//a transcript issued before the link follows its certificate, its digest does not cover the graduate
+	transcript, err := get_transcript(stub, certificate.Id)
+	if err == nil && len(transcript.GraduateId) == 0 {
+		transcript.GraduateId = graduate_id
+		err = put_transcript(stub, transcript)
+	}
+	if err != nil && as_chaincode_error(err).Code != CodeNotFound {
+		return error_response(err)
+	}
+
+	fmt.Println("- end link_certificate_graduate")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Graduate Portfolio - get a graduate and all their certificates
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	graduate_id
+//	"9f86d081884c7d65..."
+//
+// Returns - { "graduate": Graduate, "certificates": [Certificate] }
+// ============================================================================================================================
+func read_graduate_portfolio(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Portfolio struct {
+		Graduate     Graduate      `json:"graduate"`
+		Certificates []Certificate `json:"certificates"`
+	}
+	var portfolio Portfolio
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	graduate, err := get_graduate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	portfolio.Graduate = graduate
+	for _, certificate_id := range graduate.Certificates {
+		certificate, err := get_certificate(stub, certificate_id)
+		if err != nil {
+			return error_response(new_error(CodeInternal, "An error occurred while retrieving certificates"))
+		}
+		portfolio.Certificates = append(portfolio.Certificates, certificate)
+	}
+
+	err = require_graduate_reader(stub, graduate, portfolio.Certificates)
+	if err != nil {
+		return error_response(err)
+	}
+
+	portfolioAsBytes, _ := json.Marshal(portfolio) //convert to array of bytes
+	return shim.Success(portfolioAsBytes)
+}
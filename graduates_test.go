@@ -0,0 +1,133 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCertificateDocumentIsRestricted(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	stranger := func() *test_stub { return stub.as("Org9MSP", "mallory").with(nil) }
+
+	tests := []struct {
+		name     string
+		caller   func() *test_stub
+		function string
+		args     []string
+		code     string
+		document bool //whether the response may carry the document
+	}{
+		{"read", stranger, "read", []string{"c1"}, "", false},
+		{"read restricted record", stranger, "read", []string{stub.composite("certificate_document", "c1")}, CodeUnauthorized, false},
+		{"history of the restricted record", stranger, "getUniversityHistory", []string{stub.composite("certificate_document", "c1")}, CodeUnauthorized, false},
+		{"verify", stranger, "verify_certificate", []string{"c1"}, "", false},
+		{"read everything by a stranger", stranger, "read_everything", []string{"u1"}, CodeUnauthorized, false},
+		{"read everything by the dean", func() *test_stub { return stub.as("Org1MSP", "joao") }, "read_everything", []string{"u1"}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := test.caller().invoke(test.function, test.args...)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			payload := string(ok(t, res))
+			if strings.Contains(payload, "52998224725") != test.document {
+				t.Fatalf("document exposure is %v, expected %v - %s", !test.document, test.document, payload)
+			}
+		})
+	}
+}
+
+func TestGraduateLinkingAndProof(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	certificate, _ := get_certificate(stub, "c1")
+	if len(certificate.GraduateId) > 0 {
+		t.Fatal("issuers cannot derive the graduate, the certificate must start unlinked")
+	}
+
+	fails(t, stub.as("Org1MSP", "joao").with(map[string]string{"graduate_key": test_graduate_key}).invoke("link_certificate_graduate", "c1"), CodeUnauthorized)
+	ok(t, stub.as("Org1MSP", "admin").with(map[string]string{"graduate_key": test_graduate_key}).invoke("link_certificate_graduate", "c1"))
+	certificate, _ = get_certificate(stub, "c1")
+	if certificate.GraduateId != derive_graduate_id([]byte(test_graduate_key), "cpf", "52998224725") {
+		t.Fatalf("certificate linked to %q", certificate.GraduateId)
+	}
+
+	tests := []struct {
+		name string
+		cpf  string
+		code string
+	}{
+		{"own document", "529.982.247-25", ""},
+		{"another document", "111.444.777-35", CodeUnauthorized},
+		{"no document", "", CodeUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transient := map[string]string{}
+			if test.cpf != "" {
+				transient["cpf"] = test.cpf
+			}
+			res := stub.as("Org9MSP", "maria").with(transient).invoke("read_graduate_portfolio", certificate.GraduateId)
+			if test.code != "" {
+				fails(t, res, test.code)
+			} else {
+				ok(t, res)
+			}
+		})
+	}
+}
+
+func TestLinkGraduateKeepsUnreadableGraduates(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	graduateKey := stub.composite("graduate", derive_graduate_id([]byte(test_graduate_key), "cpf", "52998224725"))
+	stub.put(graduateKey, []byte("{broken"))
+
+	fails(t, stub.as("Org1MSP", "admin").with(map[string]string{"graduate_key": test_graduate_key}).invoke("link_certificate_graduate", "c1"), CodeInternal)
+	graduateAsBytes, _ := stub.GetState(graduateKey)
+	if string(graduateAsBytes) != "{broken" {
+		t.Fatalf("unreadable graduate was replaced - %s", graduateAsBytes)
+	}
+}
+
+func TestMigrationMovesTheDocument(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	stub.put("c0", map[string]interface{}{"docType": "certificate", "schemaVersion": 10, "id": "c0", "name": "Ana", "document": "529.982.247-25",
+		"documentType": "cpf", "body": "Bachelor", "city": "Sao Paulo", "date": "2010-12-01", "university": map[string]string{"id": "u1"},
+		"issuedBy": "u1", "issuedAt": "2010-12-02T10:00:00Z"})
+
+	ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "100"))
+	var fields map[string]interface{}
+	certificateAsBytes, _ := stub.GetState("c0")
+	json.Unmarshal(certificateAsBytes, &fields)
+	if _, found := fields["document"]; found {
+		t.Fatalf("migrated certificate still carries the document - %s", certificateAsBytes)
+	}
+	certificate, err := get_certificate(stub, "c0")
+	if err != nil || certificate.Document != "52998224725" || certificate.DocumentType != "cpf" {
+		t.Fatalf("document was not kept - %+v %v", certificate, err)
+	}
+}
@@ -20,11 +20,18 @@ under the License.
 package main
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"strconv"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
 )
 
 // ============================================================================================================================
@@ -37,16 +44,82 @@ func get_certificate(stub shim.ChaincodeStubInterface, id string) (Certificate,
 		//this seems to always succeed, even if key didn't exist
 		return certificate, errors.New("Failed to find certificate - " + id)
 	}
-	json.Unmarshal(certificateAsBytes, &certificate) //un stringify it aka JSON.parse()
+	if certificateAsBytes == nil {
+		return certificate, new_error(CodeNotFound, "Certificate does not exist - "+id)
+	}
+	certificate, err = decode_certificate(certificateAsBytes) //un stringify it, upgrading older schema versions
+	if err != nil {
+		return certificate, errors.New("Certificate is not readable - " + id + " - " + err.Error())
+	}
 
 	if certificate.Id != id {
 		//test if certificate is actually here or just nil
-		return certificate, errors.New("Certificate does not exist - " + id)
+		return certificate, new_error(CodeNotFound, "Certificate does not exist - "+id)
+	}
+
+	//the document lives in its own restricted record, certificates stored before that still carry it
+	if len(certificate.Document) == 0 {
+		document, err := get_certificate_document(stub, id)
+		if err == nil {
+			certificate.Document = document.Document
+			certificate.DocumentType = document.DocumentType
+		} else if as_chaincode_error(err).Code != CodeNotFound {
+			return certificate, err
+		}
 	}
 
 	return certificate, nil
 }
 
+// ========================================================
+// Get Certificate Document - get the restricted document record of a certificate from ledger
+// ========================================================
+func get_certificate_document(stub shim.ChaincodeStubInterface, certificate_id string) (CertificateDocument, error) {
+	var document CertificateDocument
+	documentKey, _ := stub.CreateCompositeKey("certificate_document", []string{certificate_id})
+	documentAsBytes, err := stub.GetState(documentKey)
+	if err != nil {
+		return document, errors.New("Failed to get certificate document - " + certificate_id)
+	}
+	if documentAsBytes == nil {
+		return document, new_error(CodeNotFound, "Certificate has no document - "+certificate_id)
+	}
+	err = json.Unmarshal(documentAsBytes, &document) //un stringify it aka JSON.parse()
+	if err != nil {
+		return document, errors.New("Certificate document is not readable - " + certificate_id)
+	}
+	return document, nil
+}
+
+// ========================================================
+// Put Certificate - store a certificate, its document goes to the restricted certificate_document record
+// ========================================================
+func put_certificate(stub shim.ChaincodeStubInterface, certificate Certificate) error {
+	var document CertificateDocument
+	document.ObjectType = "certificate_document"
+	document.SchemaVersion = CertificateDocumentSchemaVersion
+	document.CertificateId = certificate.Id
+	document.Document = certificate.Document
+	document.DocumentType = certificate.DocumentType
+	documentKey, _ := stub.CreateCompositeKey("certificate_document", []string{certificate.Id})
+	err := put_asset(stub, documentKey, &document)
+	if err != nil {
+		return err
+	}
+
+	certificate = public_certificate(certificate)
+	return put_asset(stub, certificate.Id, &certificate) //store certificate with id as key
+}
+
+// ========================================================
+// Public Certificate - the certificate without the personal document, what ungated readers get
+// ========================================================
+func public_certificate(certificate Certificate) Certificate {
+	certificate.Document = ""
+	certificate.DocumentType = ""
+	return certificate
+}
+
 // ============================================================================================================================
 // Get University - get the university asset from ledger
 // ============================================================================================================================
@@ -57,11 +130,17 @@ func get_university(stub shim.ChaincodeStubInterface, id string) (University, er
 		//this seems to always succeed, even if key didn't exist
 		return university, errors.New("Failed to get university - " + id)
 	}
-	json.Unmarshal(universityAsBytes, &university) //un stringify it aka JSON.parse()
+	if universityAsBytes == nil {
+		return university, new_error(CodeNotFound, "University does not exist - "+id)
+	}
+	university, err = decode_university(universityAsBytes) //un stringify it, upgrading older schema versions
+	if err != nil {
+		return university, errors.New("University is not readable - " + id + " - " + err.Error())
+	}
 
 	if len(university.UniversityName) == 0 {
 		//test if university is actually here or just nil
-		return university, errors.New("University does not exist - " + id + ", '" + university.UniversityName + "' '" + university.UniversityName + "'")
+		return university, new_error(CodeNotFound, "University does not exist - "+id+", '"+university.UniversityName+"' '"+university.UniversityName+"'")
 	}
 
 	return university, nil
@@ -73,11 +152,92 @@ func get_university(stub shim.ChaincodeStubInterface, id string) (University, er
 func sanitize_arguments(strs []string) error {
 	for i, val := range strs {
 		if len(val) <= 0 {
-			return errors.New("Argument " + strconv.Itoa(i) + " must be a non-empty string")
+			return new_error(CodeInvalidArgument, "Argument "+strconv.Itoa(i)+" must be a non-empty string")
 		}
 		/*if len(val) > 32 {
-			return errors.New("Argument " + strconv.Itoa(i) + " must be <= 32 characters")
+			return new_error(CodeInvalidArgument, "Argument " + strconv.Itoa(i) + " must be <= 32 characters")
 		}*/
 	}
 	return nil
 }
+
+// ========================================================
+// Get Creator - read the identity that submitted the transaction
+// ========================================================
+func get_creator(stub shim.ChaincodeStubInterface) (Identity, error) {
+	var identity Identity
+	var serializedIdentity msp.SerializedIdentity
+
+	creatorAsBytes, err := stub.GetCreator()
+	if err != nil {
+		return identity, errors.New("Failed to get transaction creator - " + err.Error())
+	}
+	err = proto.Unmarshal(creatorAsBytes, &serializedIdentity)
+	if err != nil {
+		return identity, errors.New("Failed to decode transaction creator - " + err.Error())
+	}
+
+	block, _ := pem.Decode(serializedIdentity.IdBytes)
+	if block == nil {
+		return identity, errors.New("Transaction creator is not a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return identity, errors.New("Failed to parse creator certificate - " + err.Error())
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	identity.Id = serializedIdentity.Mspid + ":" + cert.Subject.CommonName
+	identity.MspId = serializedIdentity.Mspid
+	identity.CommonName = cert.Subject.CommonName
+	identity.OrgUnits = cert.Subject.OrganizationalUnit
+	identity.Fingerprint = hex.EncodeToString(fingerprint[:])
+	return identity, nil
+}
+
+// ========================================================
+// Get Tx Time - the transaction timestamp, the only clock every endorser agrees on
+// ========================================================
+func get_tx_time(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errors.New("Failed to get transaction timestamp - " + err.Error())
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
+}
+
+// ========================================================
+// Parse Day - parse a YYYY-MM-DD date
+// ========================================================
+func parse_day(str string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", str)
+	if err != nil {
+		return day, new_error(CodeInvalidArgument, "'"+str+"' is not a YYYY-MM-DD date")
+	}
+	return day, nil
+}
+
+// ========================================================
+// Contains - the list holds the value
+// ========================================================
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ========================================================
+// Only Digits - drop everything but 0-9, documents are compared without their punctuation
+// ========================================================
+func only_digits(str string) string {
+	var digits []byte
+	for i := 0; i < len(str); i++ {
+		if str[i] >= '0' && str[i] <= '9' {
+			digits = append(digits, str[i])
+		}
+	}
+	return string(digits)
+}
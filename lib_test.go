@@ -0,0 +1,288 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Test Stub ----- //
+// a MockStub with the creator, transient map and key history the 1.4 MockStub leaves unimplemented
+type test_stub struct {
+	*shim.MockStub
+	args      []string
+	creator   []byte
+	transient map[string][]byte
+	history   map[string][]*queryresult.KeyModification
+	clock     time.Time //timestamp of the next transaction, moves a minute per transaction
+	txs       int
+}
+
+func new_test_stub() *test_stub {
+	return &test_stub{
+		MockStub: shim.NewMockStub("aioncc", new(AionCertsChainCode)),
+		history:  make(map[string][]*queryresult.KeyModification),
+		clock:    time.Date(2017, 8, 4, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (s *test_stub) GetFunctionAndParameters() (string, []string) {
+	if len(s.args) == 0 {
+		return "", []string{}
+	}
+	return s.args[0], s.args[1:]
+}
+
+func (s *test_stub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *test_stub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *test_stub) PutState(key string, value []byte) error {
+	err := s.MockStub.PutState(key, value)
+	if err == nil {
+		s.record(key, value)
+	}
+	return err
+}
+
+func (s *test_stub) DelState(key string) error {
+	err := s.MockStub.DelState(key)
+	if err == nil {
+		s.record(key, nil)
+	}
+	return err
+}
+
+func (s *test_stub) record(key string, value []byte) {
+	at := s.TxTimestamp
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.TxID, Value: value, Timestamp: at, IsDelete: value == nil})
+}
+
+func (s *test_stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &test_history{modifications: s.history[key]}, nil
+}
+
+type test_history struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (h *test_history) HasNext() bool { return len(h.modifications) > 0 }
+func (h *test_history) Close() error  { return nil }
+func (h *test_history) Next() (*queryresult.KeyModification, error) {
+	next := h.modifications[0]
+	h.modifications = h.modifications[1:]
+	return next, nil
+}
+
+// as - submit the next transactions as "<msp>:<cn>"
+func (s *test_stub) as(mspId string, cn string) *test_stub {
+	s.creator = test_identity(mspId, cn)
+	return s
+}
+
+// with - send the transient map with the next transactions
+func (s *test_stub) with(transient map[string]string) *test_stub {
+	s.transient = make(map[string][]byte)
+	for k, v := range transient {
+		s.transient[k] = []byte(v)
+	}
+	return s
+}
+
+// begin - open a transaction at the stub clock
+func (s *test_stub) begin() {
+	s.txs++
+	s.MockTransactionStart("tx" + strconv.Itoa(s.txs))
+	s.TxTimestamp = &timestamp.Timestamp{Seconds: s.clock.Unix(), Nanos: int32(s.clock.Nanosecond())}
+	s.clock = s.clock.Add(time.Minute)
+}
+
+// end - close the transaction opened by begin
+func (s *test_stub) end() {
+	s.MockTransactionEnd(s.TxID)
+}
+
+func (s *test_stub) init(args ...string) pb.Response {
+	s.args = append([]string{"init"}, args...)
+	s.begin()
+	defer s.end()
+	return new(AionCertsChainCode).Init(s)
+}
+
+func (s *test_stub) invoke(function string, args ...string) pb.Response {
+	s.args = append([]string{function}, args...)
+	s.begin()
+	defer s.end()
+	return new(AionCertsChainCode).Invoke(s)
+}
+
+// put - write a record directly, as state left by an earlier chaincode version
+func (s *test_stub) put(key string, value interface{}) {
+	valueAsBytes, ok := value.([]byte)
+	if !ok {
+		valueAsBytes, _ = json.Marshal(value)
+	}
+	s.begin()
+	defer s.end()
+	s.PutState(key, valueAsBytes)
+}
+
+// composite - a composite key, for reading state directly
+func (s *test_stub) composite(objectType string, attributes ...string) string {
+	key, _ := s.CreateCompositeKey(objectType, attributes)
+	return key
+}
+
+var test_identities = make(map[string][]byte)
+
+// test_identity - a serialized identity with a self-signed certificate for the common name
+func test_identity(mspId string, cn string) []byte {
+	if serialized, ok := test_identities[mspId+":"+cn]; ok {
+		return serialized
+	}
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(test_identities) + 1)),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	serialized, _ := proto.Marshal(&msp.SerializedIdentity{Mspid: mspId, IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})})
+	test_identities[mspId+":"+cn] = serialized
+	return serialized
+}
+
+// ok - fail the test unless the response succeeded
+func ok(t testing.TB, res pb.Response) []byte {
+	t.Helper()
+	if res.Status != shim.OK {
+		t.Fatalf("expected success, got %d %s", res.Status, res.Message)
+	}
+	return res.Payload
+}
+
+// fails - fail the test unless the response is an error with the code
+func fails(t testing.TB, res pb.Response, code string) ChaincodeError {
+	t.Helper()
+	var chaincodeError ChaincodeError
+	if res.Status == shim.OK {
+		t.Fatalf("expected %s, got success %s", code, res.Payload)
+	}
+	if err := json.Unmarshal([]byte(res.Message), &chaincodeError); err != nil {
+		t.Fatalf("error is not a JSON ChaincodeError - %s", res.Message)
+	}
+	if chaincodeError.Code != code {
+		t.Fatalf("expected %s, got %s %s", code, chaincodeError.Code, chaincodeError.Message)
+	}
+	return chaincodeError
+}
+
+// split_identity - the msp and common name of "<msp>:<common name>"
+func split_identity(identity string) (string, string) {
+	for i := range identity {
+		if identity[i] == ':' {
+			return identity[:i], identity[i+1:]
+		}
+	}
+	return identity, ""
+}
+
+const test_admin = "Org1MSP:admin"
+
+// new_test_ledger - a test stub with the chaincode config in place, test_admin as its admin and MECMSP as regulator
+func new_test_ledger() *test_stub {
+	stub := new_test_stub()
+	stub.as("Org1MSP", "admin")
+	stub.init(`{"regulatorMsp": "MECMSP", "admins": ["` + test_admin + `"]}`)
+	return stub
+}
+
+const test_graduate_key = "graduate-key"
+const test_cpf = "529.982.247-25"
+const test_cnpj = "11.222.333/0001-81"
+
+// new_issuing_ledger - a test ledger where university u1, accredited by MEC, may issue certificates of program p1
+//
+// The dean of u1 signs as Org1MSP:joao and MEC accredits as MECMSP:inspector.
+func new_issuing_ledger(t testing.TB) *test_stub {
+	t.Helper()
+	digest := sha256.Sum256([]byte(test_graduate_key))
+	stub := new_test_stub()
+	stub.as("Org1MSP", "admin")
+	ok(t, stub.init(`{"regulatorMsp": "MECMSP", "admins": ["`+test_admin+`"], "graduateKeyDigest": "`+hex.EncodeToString(digest[:])+`"}`))
+	ok(t, stub.invoke("init_accreditor", `{"id": "a1", "name": "MEC", "mspId": "MECMSP"}`))
+	stub.as("MECMSP", "inspector")
+	ok(t, stub.invoke("init_university", `{"id": "u1", "dean": "Joao", "dean_identity": "Org1MSP:joao", "name": "Universidade Um", "document": "`+test_cnpj+`"}`))
+	ok(t, stub.invoke("accredit_university", `{"university_id": "u1", "scope": ["bachelor"], "act": "Portaria 1", "valid_from": "2000-01-01", "valid_until": "2099-12-31"}`))
+	ok(t, stub.invoke("init_program", `{"id": "p1", "university_id": "u1", "name": "Computer Science", "level": "bachelor", "field_of_study": "Computing",
+		"credit_hours": 3200, "emec_code": "1234", "recognition_act": "Portaria 2", "recognition_valid_from": "2000-01-01", "recognition_valid_until": "2099-12-31"}`))
+	stub.as("Org1MSP", "joao")
+	return stub
+}
+
+// issue - init_cert by the dean of u1, from a payload of the certificate fields
+func (s *test_stub) issue(fields map[string]interface{}) pb.Response {
+	return s.issue_as("Org1MSP", "joao", fields)
+}
+
+// issue_as - init_cert by "<msp>:<cn>", from a payload of the certificate fields
+func (s *test_stub) issue_as(mspId string, cn string, fields map[string]interface{}) pb.Response {
+	payload := map[string]interface{}{
+		"id": "c1", "name": "Maria", "document": test_cpf, "body": "Bachelor of Computer Science", "city": "Sao Paulo",
+		"date": "2017-07-20", "university_id": "u1", "university_doc": test_cnpj, "program_id": "p1",
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+	payloadAsBytes, _ := json.Marshal(payload)
+	return s.as(mspId, cn).with(nil).invoke("init_cert", string(payloadAsBytes))
+}
+
+// add_university - register and accredit another university, by MEC, whose dean signs as "<mspId>:dean"
+func (s *test_stub) add_university(t testing.TB, id string, cnpj string, mspId string) {
+	t.Helper()
+	s.as("MECMSP", "inspector")
+	ok(t, s.invoke("init_university", `{"id": "`+id+`", "dean": "Dean", "dean_identity": "`+mspId+`:dean", "name": "University `+id+`", "document": "`+cnpj+`"}`))
+	ok(t, s.invoke("accredit_university", `{"university_id": "`+id+`", "scope": ["bachelor"], "act": "Portaria 3", "valid_from": "2000-01-01", "valid_until": "2099-12-31"}`))
+}
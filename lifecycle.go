@@ -0,0 +1,487 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ========================================================
+// Set University Status - change the lifecycle status of a university and store it
+//
+// Every change is its own write, so getUniversityHistory shows each step with its reason.
+// ========================================================
+func set_university_status(stub shim.ChaincodeStubInterface, university University, status string, reason string) error {
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return err
+	}
+	university.Status = status
+	university.StatusReason = reason
+	university.StatusChanged = now.Format(time.RFC3339)
+
+	return put_asset(stub, university.Id, &university) //rewrite the university with id as key
+}
+
+// ========================================================
+// Get Responsible University - the university that answers for the records of the given one
+//
+// Follows successors of merged universities and custodians of closed ones until it reaches one that is neither.
+// ========================================================
+func get_responsible_university(stub shim.ChaincodeStubInterface, university University) (University, error) {
+	visited := make(map[string]bool)
+	for {
+		if visited[university.Id] {
+			return university, new_error(CodeConflict, "University '"+university.Id+"' is part of a successor/custodian cycle")
+		}
+		visited[university.Id] = true
+
+		var next string
+		if university.Status == "merged" {
+			next = university.Successor
+		} else if university.Status == "closed" {
+			if len(university.Custodian) == 0 {
+				return university, new_error(CodeConflict, "University '"+university.Id+"' is closed and has no custodian assigned")
+			}
+			next = university.Custodian
+		} else {
+			return university, nil
+		}
+
+		successor, err := get_university(stub, next)
+		if err != nil {
+			return university, err
+		}
+		university = successor
+	}
+}
+
+// ========================================================
+// Change Status - shared body of the suspend/reactivate/close functions
+// ========================================================
+func change_status(stub shim.ChaincodeStubInterface, args []string, status string, allowed_from ...string) pb.Response {
+	var err error
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	university_id := args[0]
+	reason := args[1]
+
+	_, err = require_accreditor_of(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	university, err := get_university(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	allowed := false
+	for _, from := range allowed_from {
+		if university.Status == from {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return error_response(new_error(CodeConflict, "University '"+university_id+"' is "+university.Status+" and cannot become "+status))
+	}
+
+	err = set_university_status(stub, university, status, reason)
+	if err != nil {
+		return error_response(err)
+	}
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Suspend University - block new issuance, accreditor of record or regulator only
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | reason
+//
+// "u123"         | "Supervision process 123"
+// ============================================================================================================================
+func suspend_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("starting suspend_university")
+	return change_status(stub, args, "suspended", "active")
+}
+
+// ============================================================================================================================
+// Reactivate University - lift a suspension, accreditor of record or regulator only
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | reason
+//
+// "u123"         | "Supervision process 123 archived"
+// ============================================================================================================================
+func reactivate_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("starting reactivate_university")
+	return change_status(stub, args, "active", "suspended")
+}
+
+// ============================================================================================================================
+// Close University - close a university for good, its certificates stay verifiable
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | reason
+//
+// "u123"         | "Closed by its owners"
+// ============================================================================================================================
+func close_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("starting close_university")
+	return change_status(stub, args, "closed", "active", "suspended")
+}
+
+// ============================================================================================================================
+// Assign Custodian - set the university answering for a closed university's records
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | custodian_id
+//
+// "u123"         | "u456"
+// ============================================================================================================================
+func assign_custodian(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting assign_custodian")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	university_id := args[0]
+	custodian_id := args[1]
+
+	_, err = require_accreditor_of(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	university, err := get_university(stub, university_id)
+	if err != nil {
+		return error_response(err)
+	}
+	if university.Status != "closed" {
+		return error_response(new_error(CodeConflict, "University '"+university_id+"' is "+university.Status+", only closed universities get a custodian"))
+	}
+
+	custodian, err := get_university(stub, custodian_id)
+	if err != nil {
+		return error_response(err)
+	}
+	if custodian.Id == university.Id || custodian.Status != "active" {
+		return error_response(new_error(CodeInvalidArgument, "University '"+custodian_id+"' cannot be custodian of '"+university_id+"'"))
+	}
+
+	university.Custodian = custodian.Id
+	err = set_university_status(stub, university, university.Status, "Custodian assigned - "+custodian.Id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end assign_custodian")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Verify Certificate - check a certificate against its issuer and tell who answers for it today
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// ============================================================================================================================
+func verify_certificate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Verification struct {
+		Certificate  Certificate `json:"certificate"`
+		IssuerStatus string      `json:"issuerStatus"` //current status of the original issuer
+		Responsible  string      `json:"responsible"`  //university answering for the record today
+		Valid        bool        `json:"valid"`
+		Problems     []string    `json:"problems"`
+	}
+	var verification Verification
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	certificate, err := get_certificate(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	verification.Certificate = public_certificate(certificate)
+	verification.Valid = true
+
+	issuer, err := get_university(stub, certificate.University.Id)
+	if err != nil {
+		verification.Valid = false
+		verification.Problems = append(verification.Problems, error_message(err))
+	} else {
+		verification.IssuerStatus = issuer.Status
+		responsible, err := get_responsible_university(stub, issuer)
+		if err != nil {
+			verification.Problems = append(verification.Problems, error_message(err)) //still valid, just nobody to ask
+		} else {
+			verification.Responsible = responsible.Id
+		}
+	}
+
+	// the signer must have been dean of the issuing university when the record was written
+	if len(certificate.DeanTerm) == 0 {
+		verification.Problems = append(verification.Problems, "Issued before dean terms were recorded, signer unknown")
+	} else {
+		issuedAt, err := certificate_issued_at(stub, certificate)
+		if err == nil {
+			err = check_signature_at(stub, certificate, issuedAt)
+		}
+		if err != nil {
+			verification.Valid = false
+			verification.Problems = append(verification.Problems, error_message(err))
+		}
+	}
+
+	fmt.Println("- end verify_certificate")
+	verificationAsBytes, _ := json.Marshal(verification) //convert to array of bytes
+	return shim.Success(verificationAsBytes)
+}
+
+// ============================================================================================================================
+// Issue Second Copy - issue a copy of a certificate by the university answering for it
+//
+// The copy keeps the original issuer in its university block and records who issued it in issuedBy.
+//
+// Inputs - Array of Strings
+//
+//	0              | 1       | 2                          | 3
+//	certificate_id | copy_id | responsible_university_doc | delegation_id (optional, when a registrar submits)
+//
+// "c123"          | "c124"  | "11.222.333/0001-81"       | "d123"
+// ============================================================================================================================
+func issue_second_copy(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting issue_second_copy")
+
+	if len(args) != 3 && len(args) != 4 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3 or 4"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	certificate_id := args[0]
+	copy_id := args[1]
+	university_doc := args[2]
+	var delegation_id string
+	if len(args) == 4 {
+		delegation_id = args[3]
+	}
+
+	original, err := get_certificate(stub, certificate_id)
+	if err != nil {
+		return error_response(err)
+	}
+	issuer, err := get_university(stub, original.University.Id)
+	if err != nil {
+		return error_response(err)
+	}
+	responsible, err := get_responsible_university(stub, issuer)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//the responsible university must be able to issue today
+	if !same_cnpj(responsible.Document, university_doc) {
+		return error_response(new_error(CodeUnauthorized, "The university '"+responsible.UniversityName+"' cannot authorize creation for university '"+university_doc+"'."))
+	}
+	//a copy issued by the original university keeps its unit and program, which scope the delegation
+	var units []string
+	var program_id string
+	if responsible.Id == original.IssuedBy {
+		program_id = original.ProgramId
+		if len(original.UnitId) > 0 {
+			units, err = get_unit_chain(stub, responsible.Id, original.UnitId)
+			if err != nil {
+				return error_response(err)
+			}
+		}
+	}
+	signer, err := authorize_issuance(stub, responsible, delegation_id, program_id, units)
+	if err != nil {
+		return error_response(err)
+	}
+	if responsible.Status != "active" {
+		return error_response(new_error(CodeConflict, "University '"+responsible.Id+"' is "+responsible.Status+" and cannot issue certificates"))
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	err = check_accreditation(stub, responsible.Id, now)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check if the copy id is free, any record under it would be overwritten
+	existingAsBytes, err := stub.GetState(copy_id)
+	if err != nil {
+		return error_response(wrap_error("Failed to get certificate", err))
+	}
+	if existingAsBytes != nil {
+		return error_response(new_error(CodeAlreadyExists, "A record already exists under the certificate id - "+copy_id))
+	}
+
+	certificate := original
+	certificate.Provenance = Provenance{} //the copy is a new record, created by this transaction
+	certificate.Id = copy_id
+	certificate.CopyOf = original.Id
+	certificate.IssuedBy = responsible.Id
+	certificate.DeanTerm = signer.Term.Id
+	certificate.SignedBy = signer.Identity
+	certificate.Delegation = signer.Delegation
+	certificate.IssuedAt = now.Format(time.RFC3339Nano)
+	if len(units) == 0 {
+		certificate.UnitId = "" //units belong to the original university only
+	}
+	err = put_certificate(stub, certificate)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//the copy belongs to the same graduate
+	if len(certificate.GraduateId) > 0 {
+		err = link_graduate(stub, certificate.GraduateId, certificate.Id)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+
+	//the copy is listed with the university, and unit, that issued it
+	if len(certificate.UnitId) > 0 {
+		err = index_certificate_unit(stub, responsible.Id, certificate.UnitId, certificate.Id)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+	responsible.Certificates = append(responsible.Certificates, certificate.Id)
+	err = put_asset(stub, responsible.Id, &responsible) //store university by its Id
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end issue_second_copy")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Merge Universities - merge a university into a successor, accreditor of record of the absorbed university only
+//
+// The absorbed university stops issuing, its certificates keep it as their issuer and resolve to the successor
+// for verification and second copies.
+//
+// Inputs - Array of Strings
+//
+//	0           | 1            | 2
+//	absorbed_id | successor_id | reason
+//
+// "u123"       | "u456"       | "Acquired by Grupo Educacional X"
+// ============================================================================================================================
+func merge_universities(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting merge_universities")
+
+	if len(args) != 3 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	absorbed_id := args[0]
+	successor_id := args[1]
+	reason := args[2]
+
+	_, err = require_accreditor_of(stub, absorbed_id)
+	if err != nil {
+		return error_response(err)
+	}
+
+	absorbed, err := get_university(stub, absorbed_id)
+	if err != nil {
+		return error_response(err)
+	}
+	if absorbed.Status != "active" && absorbed.Status != "suspended" {
+		return error_response(new_error(CodeConflict, "University '"+absorbed_id+"' is "+absorbed.Status+" and cannot be merged"))
+	}
+
+	successor, err := get_university(stub, successor_id)
+	if err != nil {
+		return error_response(err)
+	}
+	if successor.Id == absorbed.Id || successor.Status != "active" {
+		return error_response(new_error(CodeInvalidArgument, "University '"+successor_id+"' cannot be the successor of '"+absorbed_id+"'"))
+	}
+
+	// record the relationship on both sides
+	successor.Absorbed = append(successor.Absorbed, absorbed.Id)
+	err = put_asset(stub, successor.Id, &successor) //rewrite the university with id as key
+	if err != nil {
+		return error_response(err)
+	}
+
+	absorbed.Successor = successor.Id
+	err = set_university_status(stub, absorbed, "merged", reason)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end merge_universities")
+	return shim.Success(nil)
+}
@@ -0,0 +1,189 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUniversityStatusTransitions(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	steps := []struct {
+		function string
+		caller   string
+		code     string
+		status   string //status of u1 afterwards
+	}{
+		{"suspend_university", "Org9MSP:mallory", CodeUnauthorized, "active"},
+		{"suspend_university", "Org1MSP:joao", CodeUnauthorized, "active"},
+		{"reactivate_university", "MECMSP:inspector", CodeConflict, "active"},
+		{"suspend_university", "MECMSP:inspector", "", "suspended"},
+		{"suspend_university", "MECMSP:inspector", CodeConflict, "suspended"},
+		{"reactivate_university", "MECMSP:inspector", "", "active"},
+		{"close_university", "MECMSP:inspector", "", "closed"},
+		{"reactivate_university", "MECMSP:inspector", CodeConflict, "closed"},
+		{"suspend_university", "MECMSP:inspector", CodeConflict, "closed"},
+	}
+	for i, step := range steps {
+		mspId, cn := split_identity(step.caller)
+		res := stub.as(mspId, cn).invoke(step.function, "u1", "Supervision process 123")
+		if step.code != "" {
+			fails(t, res, step.code)
+		} else {
+			ok(t, res)
+		}
+		university, _ := get_university(stub, "u1")
+		if university.Status != step.status {
+			t.Fatalf("step %d, %s - u1 is %s, expected %s", i, step.function, university.Status, step.status)
+		}
+	}
+}
+
+func TestSuspendedUniversityCannotIssue(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("MECMSP", "inspector").invoke("suspend_university", "u1", "Supervision process 123"))
+	fails(t, stub.issue(nil), CodeConflict)
+	ok(t, stub.as("MECMSP", "inspector").invoke("reactivate_university", "u1", "Archived"))
+	ok(t, stub.issue(nil))
+}
+
+func TestCustodianAnswersForClosedUniversity(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	stub.add_university(t, "u2", "11.444.777/0001-61", "Org2MSP")
+	ok(t, stub.as("MECMSP", "inspector").invoke("close_university", "u1", "Closed by its owners"))
+
+	type Verification struct {
+		IssuerStatus string   `json:"issuerStatus"`
+		Responsible  string   `json:"responsible"`
+		Valid        bool     `json:"valid"`
+		Problems     []string `json:"problems"`
+	}
+	var verification Verification
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("verify_certificate", "c1")), &verification)
+	if !verification.Valid || verification.Responsible != "" || len(verification.Problems) != 1 {
+		t.Fatalf("closed without custodian - %+v", verification)
+	}
+
+	fails(t, stub.as("MECMSP", "inspector").invoke("assign_custodian", "u1", "u1"), CodeInvalidArgument)
+	fails(t, stub.as("MECMSP", "inspector").invoke("assign_custodian", "u2", "u1"), CodeConflict)
+	ok(t, stub.as("MECMSP", "inspector").invoke("assign_custodian", "u1", "u2"))
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("verify_certificate", "c1")), &verification)
+	if !verification.Valid || verification.IssuerStatus != "closed" || verification.Responsible != "u2" {
+		t.Fatalf("closed with custodian - %+v", verification)
+	}
+
+	//the custodian issues second copies, the closed university no longer can
+	fails(t, stub.as("Org1MSP", "joao").invoke("issue_second_copy", "c1", "c2", test_cnpj), CodeUnauthorized)
+	ok(t, stub.as("Org2MSP", "dean").invoke("issue_second_copy", "c1", "c2", "11.444.777/0001-61"))
+	copy, err := get_certificate(stub, "c2")
+	if err != nil || copy.CopyOf != "c1" || copy.IssuedBy != "u2" || copy.University.Id != "u1" {
+		t.Fatalf("copy %+v %v", copy, err)
+	}
+}
+
+func TestSecondCopyNeedsAFreeId(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	stub.put("raw", []byte("not a certificate"))
+
+	tests := []struct {
+		name    string
+		copy_id string
+		code    string
+	}{
+		{"the original", "c1", CodeAlreadyExists},
+		{"a university", "u1", CodeAlreadyExists},
+		{"a raw value", "raw", CodeAlreadyExists},
+		{"a free id", "c2", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, _ := stub.GetState(test.copy_id)
+			res := stub.as("Org1MSP", "joao").invoke("issue_second_copy", "c1", test.copy_id, test_cnpj)
+			if test.code != "" {
+				fails(t, res, test.code)
+				if after, _ := stub.GetState(test.copy_id); string(before) != string(after) {
+					t.Fatalf("%s was overwritten", test.copy_id)
+				}
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	//the copy records its own creation, not the original's
+	original, _ := get_certificate(stub, "c1")
+	copy, err := get_certificate(stub, "c2")
+	if err != nil || copy.CreatedBy == nil || copy.CreatedBy.TxId == original.CreatedBy.TxId || copy.CreatedBy.TxId != copy.UpdatedBy.TxId {
+		t.Fatalf("copy %+v %v", copy, err)
+	}
+}
+
+func TestMergeUniversities(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	stub.add_university(t, "u2", "11.444.777/0001-61", "Org2MSP")
+	stub.add_university(t, "u3", "12.345.678/0001-95", "Org3MSP")
+	ok(t, stub.as("MECMSP", "inspector").invoke("suspend_university", "u3", "Supervision process 123"))
+
+	refused := []struct {
+		name      string
+		caller    string
+		successor string
+		code      string
+	}{
+		{"stranger", "Org9MSP:mallory", "u2", CodeUnauthorized},
+		{"dean of the absorbed university", "Org1MSP:joao", "u2", CodeUnauthorized},
+		{"into itself", "MECMSP:inspector", "u1", CodeInvalidArgument},
+		{"into a suspended university", "MECMSP:inspector", "u3", CodeInvalidArgument},
+		{"into an unknown university", "MECMSP:inspector", "u9", CodeNotFound},
+	}
+	for _, test := range refused {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			fails(t, stub.as(mspId, cn).invoke("merge_universities", "u1", test.successor, "Acquired"), test.code)
+		})
+	}
+
+	ok(t, stub.as("MECMSP", "inspector").invoke("merge_universities", "u1", "u2", "Acquired by Grupo Educacional X"))
+	absorbed, _ := get_university(stub, "u1")
+	successor, _ := get_university(stub, "u2")
+	if absorbed.Status != "merged" || absorbed.Successor != "u2" || len(successor.Absorbed) != 1 || successor.Absorbed[0] != "u1" {
+		t.Fatalf("merge was not recorded on both sides - %+v %+v", absorbed, successor)
+	}
+	fails(t, stub.as("MECMSP", "inspector").invoke("merge_universities", "u1", "u2", "Again"), CodeConflict)
+	fails(t, stub.issue(map[string]interface{}{"id": "c2"}), CodeConflict)
+
+	//certificates resolve through every merge
+	ok(t, stub.as("MECMSP", "inspector").invoke("reactivate_university", "u3", "Archived"))
+	ok(t, stub.as("MECMSP", "inspector").invoke("merge_universities", "u2", "u3", "Acquired by Grupo Educacional Y"))
+	var verification struct {
+		Certificate Certificate `json:"certificate"`
+		Responsible string      `json:"responsible"`
+		Valid       bool        `json:"valid"`
+	}
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("verify_certificate", "c1")), &verification)
+	if !verification.Valid || verification.Responsible != "u3" || verification.Certificate.University.Id != "u1" {
+		t.Fatalf("verification %+v", verification)
+	}
+	ok(t, stub.as("Org3MSP", "dean").invoke("issue_second_copy", "c1", "c2", "12.345.678/0001-95"))
+}
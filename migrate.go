@@ -0,0 +1,484 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Schema Versions - bump when the stored layout of an asset changes and teach its decoder the old one
+// ============================================================================================================================
+const (
+	CertificateSchemaVersion         = 11
+	CertificateDocumentSchemaVersion = 1
+	UniversitySchemaVersion          = 8
+	AccreditorSchemaVersion          = 2
+	AccreditationSchemaVersion       = 2
+	ConfigSchemaVersion              = 5
+	ProposalSchemaVersion            = 2
+	DeanTermSchemaVersion            = 2
+	DelegationSchemaVersion          = 2
+	UnitSchemaVersion                = 2
+	ProgramSchemaVersion             = 2
+	GraduateSchemaVersion            = 2
+	TranscriptSchemaVersion          = 2
+	CreditRecognitionSchemaVersion   = 2
+	CreditConsentSchemaVersion       = 1
+	MigrationStatusSchemaVersion     = 3
+)
+
+// ----- Additive Upgrade ----- //
+// a docType whose older versions only lack fields of the current one, they decode into the current layout as they are
+type additive_upgrade struct {
+	version int          //current schema version
+	decoded func() asset //empty record of the current layout
+}
+
+// docTypes upgraded as they are, v2 of each added provenance and every config version added settings
+var additive_upgrades = map[string]additive_upgrade{
+	"accreditor":           {AccreditorSchemaVersion, func() asset { return &Accreditor{} }},
+	"accreditation":        {AccreditationSchemaVersion, func() asset { return &Accreditation{} }},
+	"config":               {ConfigSchemaVersion, func() asset { return &ChaincodeConfig{} }},
+	"proposal":             {ProposalSchemaVersion, func() asset { return &Proposal{} }},
+	"dean_term":            {DeanTermSchemaVersion, func() asset { return &DeanTerm{} }},
+	"delegation":           {DelegationSchemaVersion, func() asset { return &Delegation{} }},
+	"unit":                 {UnitSchemaVersion, func() asset { return &Unit{} }},
+	"program":              {ProgramSchemaVersion, func() asset { return &Program{} }},
+	"graduate":             {GraduateSchemaVersion, func() asset { return &Graduate{} }},
+	"transcript":           {TranscriptSchemaVersion, func() asset { return &Transcript{} }}, //the certificate digest is kept as stored
+	"credit_recognition":   {CreditRecognitionSchemaVersion, func() asset { return &CreditRecognition{} }},
+	"credit_consent":       {CreditConsentSchemaVersion, func() asset { return &CreditConsent{} }},
+	"certificate_document": {CertificateDocumentSchemaVersion, func() asset { return &CertificateDocument{} }},
+}
+
+// key spaces of a migration pass, plain keys first and then each docType stored under composite keys, which range
+// queries over plain keys never return
+var migration_spaces = []string{"", "accreditor", "accreditation", "config", "proposal", "dean_term", "delegation", "unit", "program", "graduate", "transcript", "credit_recognition", "credit_consent", "certificate_document"}
+
+// ----- Certificate v0 ----- //
+// the first chaincode version wrote document, body, city and date into its JSON unquoted, numeric values of those
+// were stored as JSON numbers
+type CertificateV0 struct {
+	ObjectType string             `json:"docType"`
+	Id         string             `json:"id"`
+	Name       string             `json:"name"`
+	Document   legacy_text        `json:"document"`
+	Body       legacy_text        `json:"body"`
+	City       legacy_text        `json:"city"`
+	Date       legacy_text        `json:"date"`
+	University UniversityRelation `json:"university"`
+}
+
+// ----- Legacy Text ----- //
+// a v0 certificate field, a JSON string or the bare number the first chaincode version wrote in its place
+type legacy_text string
+
+func (text *legacy_text) UnmarshalJSON(data []byte) error {
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		*text = legacy_text(value)
+		return nil
+	}
+	var number json.Number
+	err := json.Unmarshal(data, &number)
+	if err != nil {
+		return err
+	}
+	*text = legacy_text(number.String()) //the digits as written, 1501810298042 stays 1501810298042
+	return nil
+}
+
+// ----- University v0 ----- //
+type UniversityV0 struct {
+	ObjectType     string   `json:"docType"`
+	Id             string   `json:"id"`
+	Dean           string   `json:"dean"`
+	UniversityName string   `json:"universityname"`
+	Document       string   `json:"document"`
+	Certificates   []string `json:"certifcates"` //misspelled in v0
+}
+
+// ----- Migration Status ----- //
+type MigrationStatus struct {
+	ObjectType    string `json:"docType"`       //field for couchdb
+	SchemaVersion int    `json:"schemaVersion"` //version of this record's layout
+	Space         string `json:"space"`         //key space the next batch starts in, "" for plain keys
+	NextKey       string `json:"nextKey"`       //key where the next batch starts
+	Scanned       int    `json:"scanned"`       //records looked at in the current pass
+	Migrated      int    `json:"migrated"`      //records rewritten in the current pass
+	Failed        int    `json:"failed"`        //records that could not be read in the current pass
+	Done          bool   `json:"done"`          //current pass reached the end of the key space
+	LastTxId      string `json:"lastTxId"`      //transaction of the last batch
+	Provenance           //who created and last wrote it, filled by put_asset
+}
+
+// ----- Migration Batch ----- //
+type MigrationBatch struct {
+	StartKey string   `json:"startKey"`
+	Space    string   `json:"space"` //key space the batch ended in
+	NextKey  string   `json:"nextKey"`
+	Scanned  int      `json:"scanned"`
+	Migrated int      `json:"migrated"`
+	Failed   []string `json:"failed"`
+}
+
+// ========================================================
+// Decode Certificate - read any known certificate version as the current one
+// ========================================================
+func decode_certificate(certificateAsBytes []byte) (Certificate, error) {
+	var certificate Certificate
+
+	version, err := read_schema_version(certificateAsBytes)
+	if err != nil {
+		return certificate, err
+	}
+
+	switch version {
+	case 0:
+		var legacy CertificateV0
+		err = json.Unmarshal(certificateAsBytes, &legacy)
+		if err != nil {
+			return certificate, err
+		}
+		certificate.ObjectType = legacy.ObjectType
+		certificate.Id = legacy.Id
+		certificate.Name = legacy.Name
+		certificate.Document = string(legacy.Document)
+		certificate.Body = string(legacy.Body)
+		certificate.City = string(legacy.City)
+		certificate.Date = string(legacy.Date)
+		certificate.University = legacy.University
+	case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11: //v1 added the version, v2 copyOf/issuedBy, v3 the signing dean term, v4 the delegation, v5 the unit, v6 the program, v7 the graduate, v8 the document type, v9 issuedAt, v10 provenance, v11 moved the document to its own record
+		err = json.Unmarshal(certificateAsBytes, &certificate)
+		if err != nil {
+			return certificate, err
+		}
+	default:
+		return certificate, errors.New("unknown certificate schema version " + strconv.Itoa(version))
+	}
+
+	if len(certificate.IssuedBy) == 0 {
+		certificate.IssuedBy = certificate.University.Id //before v2 the issuer always issued the record
+	}
+	if len(certificate.DocumentType) == 0 {
+		certificate.DocumentType = "cpf" //before v8 every graduate had a CPF
+	}
+	certificate.SchemaVersion = CertificateSchemaVersion
+	return certificate, nil
+}
+
+// ========================================================
+// Decode University - read any known university version as the current one
+// ========================================================
+func decode_university(universityAsBytes []byte) (University, error) {
+	var university University
+
+	version, err := read_schema_version(universityAsBytes)
+	if err != nil {
+		return university, err
+	}
+
+	switch version {
+	case 0:
+		var legacy UniversityV0
+		err = json.Unmarshal(universityAsBytes, &legacy)
+		if err != nil {
+			return university, err
+		}
+		university.ObjectType = legacy.ObjectType
+		university.Id = legacy.Id
+		university.Dean = legacy.Dean
+		university.UniversityName = legacy.UniversityName
+		university.Document = legacy.Document
+		university.Certificates = legacy.Certificates
+	case 1, 2, 3, 4, 5, 6, 7, 8: //v2 added the lifecycle status and custodian, v3 the successor relationship, v4 the current dean term, v5 the public key, v6 the canonical CNPJ, v7 provenance, v8 the municipality
+		err = json.Unmarshal(universityAsBytes, &university)
+		if err != nil {
+			return university, err
+		}
+	default:
+		return university, errors.New("unknown university schema version " + strconv.Itoa(version))
+	}
+
+	if len(university.Status) == 0 {
+		university.Status = "active" //before v2 every university was active
+	}
+	university.SchemaVersion = UniversitySchemaVersion
+	return university, nil
+}
+
+// ========================================================
+// Decode Additive - read any known version of an additive docType as the current one
+// ========================================================
+func decode_additive(valAsBytes []byte, upgrade additive_upgrade) (asset, error) {
+	version, err := read_schema_version(valAsBytes)
+	if err != nil {
+		return nil, err
+	}
+	if version < 1 || version > upgrade.version {
+		return nil, errors.New("unknown schema version " + strconv.Itoa(version))
+	}
+
+	record := upgrade.decoded()
+	err = json.Unmarshal(valAsBytes, record)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(`{"schemaVersion": `+strconv.Itoa(upgrade.version)+`}`), record) //stamp the current version, nothing else changes
+	return record, nil
+}
+
+// ========================================================
+// Read Schema Version - peek at the version of a stored record, records without one are v0
+// ========================================================
+func read_schema_version(valAsBytes []byte) (int, error) {
+	var header struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	err := json.Unmarshal(valAsBytes, &header)
+	if err != nil {
+		return 0, errors.New("not a valid JSON document - " + err.Error())
+	}
+	return header.SchemaVersion, nil
+}
+
+// ============================================================================================================================
+// Migrate State - upgrade stored records to the current schema versions, one batch per call
+//
+// Progress is kept on the ledger so a pass can be resumed by calling again without a start key.
+// Once a pass is done the next call starts a new pass from the beginning. A pass walks the plain keys, then the
+// composite keys of each docType in migration_spaces.
+//
+// Inputs - Array of strings
+//
+//	0          | 1
+//	batch_size | start_key (optional, restart the pass from this key)
+//	"100"      | "c123"
+//
+// Returns - { "batch": MigrationBatch, "status": MigrationStatus }
+// ============================================================================================================================
+func migrate_state(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting migrate_state")
+
+	if len(args) != 1 && len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting batch size and optional start key"))
+	}
+
+	err = require_admin(stub)
+	if err != nil {
+		return error_response(err)
+	}
+
+	batch_size, err := strconv.Atoi(args[0])
+	if err != nil || batch_size <= 0 {
+		return error_response(new_error(CodeInvalidArgument, "Batch size must be a positive integer"))
+	}
+
+	statusKey, _ := stub.CreateCompositeKey("migration", []string{"status"})
+	var status MigrationStatus
+	statusAsBytes, err := stub.GetState(statusKey)
+	if err != nil {
+		return error_response(wrap_error("Failed to get migration status", err))
+	}
+	if statusAsBytes != nil {
+		json.Unmarshal(statusAsBytes, &status) //un stringify it aka JSON.parse()
+	}
+	if status.Done || len(args) == 2 {
+		status = MigrationStatus{} //start a new pass
+	}
+	if len(args) == 2 {
+		status.NextKey = args[1]
+	}
+	status.ObjectType = "migration_status"
+	status.SchemaVersion = MigrationStatusSchemaVersion
+
+	var batch MigrationBatch
+	batch.StartKey = status.NextKey
+	space := 0
+	for i, name := range migration_spaces {
+		if name == status.Space {
+			space = i
+		}
+	}
+	for ; space < len(migration_spaces); space++ {
+		batch.Space = migration_spaces[space]
+		err = migrate_space(stub, batch.Space, status.NextKey, batch_size, &batch)
+		if err != nil {
+			return error_response(err)
+		}
+		if len(batch.NextKey) > 0 {
+			break //batch is full
+		}
+		status.NextKey = "" //the next space starts from its first key
+	}
+
+	status.Space = batch.Space
+	status.NextKey = batch.NextKey
+	status.Done = len(batch.NextKey) == 0
+	if status.Done {
+		status.Space = ""
+	}
+	status.Scanned += batch.Scanned
+	status.Migrated += batch.Migrated
+	status.Failed += len(batch.Failed)
+	status.LastTxId = stub.GetTxID()
+	err = put_asset(stub, statusKey, &status)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end migrate_state, migrated " + strconv.Itoa(batch.Migrated) + " of " + strconv.Itoa(batch.Scanned))
+	resultAsBytes, _ := json.Marshal(map[string]interface{}{"batch": batch, "status": status})
+	return shim.Success(resultAsBytes)
+}
+
+// ========================================================
+// Migrate Space - migrate the records of one key space from start_key on until the batch is full
+//
+// A full batch leaves the first key of the next batch in batch.NextKey.
+// ========================================================
+func migrate_space(stub shim.ChaincodeStubInterface, space string, start_key string, batch_size int, batch *MigrationBatch) error {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	if len(space) == 0 {
+		resultsIterator, err = stub.GetStateByRange(start_key, "")
+	} else {
+		resultsIterator, err = stub.GetStateByPartialCompositeKey(space, []string{})
+	}
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		if len(space) == 0 && strings.HasPrefix(record.Key, "\x00") {
+			continue //composite keys have their own spaces
+		}
+		if record.Key < start_key {
+			continue //partial composite key queries cannot start mid-way, skip what earlier batches did
+		}
+		if batch.Scanned == batch_size {
+			batch.NextKey = record.Key //first key of the next batch
+			return nil
+		}
+
+		batch.Scanned++
+		migrated, err := migrate_record(stub, record.Key, record.Value)
+		if err != nil {
+			batch.Failed = append(batch.Failed, record.Key+" - "+error_message(err))
+		} else if migrated {
+			batch.Migrated++
+		}
+	}
+	return nil
+}
+
+// ========================================================
+// Migrate Record - rewrite one record if it is an outdated asset, reports whether it was rewritten
+// ========================================================
+func migrate_record(stub shim.ChaincodeStubInterface, key string, valAsBytes []byte) (bool, error) {
+	var header struct {
+		ObjectType    string `json:"docType"`
+		SchemaVersion int    `json:"schemaVersion"`
+	}
+	if len(valAsBytes) == 0 || valAsBytes[0] != '{' {
+		return false, nil //not an asset, e.g. a raw value left by an earlier version
+	}
+	err := json.Unmarshal(valAsBytes, &header)
+	if err != nil {
+		return false, errors.New("not a valid JSON document")
+	}
+
+	var upgraded asset
+	switch header.ObjectType {
+	case "certificate":
+		if header.SchemaVersion == CertificateSchemaVersion {
+			return false, nil
+		}
+		certificate, err := decode_certificate(valAsBytes)
+		if err != nil {
+			return false, err
+		}
+		//store the document and date in canonical form, the certificate digest covers the canonical forms and stays the same
+		canonicalize_certificate_cpf(&certificate)
+		certificate.Date, _ = canonical_date(certificate.Date) //dates that never parsed are kept as issued
+		if len(certificate.IssuedAt) == 0 {
+			issuedAt, err := get_issued_at(stub, certificate.Id)
+			if err != nil {
+				return false, err
+			}
+			certificate.IssuedAt = issuedAt.Format(time.RFC3339Nano)
+		}
+		//the document moves out to its own record
+		err = put_certificate(stub, certificate)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	case "university":
+		if header.SchemaVersion == UniversitySchemaVersion {
+			return false, nil
+		}
+		university, err := decode_university(valAsBytes)
+		if err != nil {
+			return false, err
+		}
+		//store the CNPJ in canonical form and claim it, CNPJs that never validated are kept as issued and claimed by their digits
+		if cnpj, err := validate_cnpj(university.Document); err == nil {
+			university.Document = cnpj
+		}
+		if len(only_digits(university.Document)) > 0 {
+			err = index_university_cnpj(stub, university)
+			if err != nil {
+				return false, err
+			}
+		}
+		upgraded = &university
+	default:
+		upgrade, known := additive_upgrades[header.ObjectType]
+		if !known || header.SchemaVersion == upgrade.version {
+			return false, nil
+		}
+		upgraded, err = decode_additive(valAsBytes, upgrade)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = put_asset(stub, key, upgraded)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,206 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMigrationKeepsTranscriptDigests(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	tests := []struct {
+		id     string
+		digest func(certificate Certificate) string
+		linked bool
+	}{
+		{"c0", certificate_digest, true},
+		{"c9", func(Certificate) string { return strings.Repeat("0", 64) }, false},
+	}
+	digests := map[string]string{}
+	for _, test := range tests {
+		//issued before the document and date were canonicalized
+		certificate := Certificate{ObjectType: "certificate", SchemaVersion: 8, Id: test.id, Name: "Ana", Document: "529.982.247-25", DocumentType: "cpf",
+			Body: "Bachelor", City: "Sao Paulo", Date: "20101201", University: UniversityRelation{Id: "u1"}, IssuedBy: "u1"}
+		stub.put(test.id, certificate)
+		digests[test.id] = test.digest(certificate)
+		stub.put(stub.composite("transcript", test.id), map[string]interface{}{"docType": "transcript", "schemaVersion": 1, "certificateId": test.id,
+			"certificateDigest": digests[test.id], "courses": []Course{{Code: "MAT101", Name: "Calculus", Term: "2010/1", Grade: 8, CreditHours: 60, Status: "passed"}}})
+	}
+
+	ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "100"))
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			transcript, err := get_transcript(stub, test.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transcript.CertificateDigest != digests[test.id] || transcript.SchemaVersion != TranscriptSchemaVersion {
+				t.Fatalf("transcript was not kept as stored - %+v", transcript)
+			}
+			var result struct {
+				Linked bool `json:"linked"`
+			}
+			json.Unmarshal(ok(t, stub.as("Org1MSP", "joao").invoke("read_transcript", test.id)), &result)
+			if result.Linked != test.linked {
+				t.Fatalf("linked is %v after the migration", result.Linked)
+			}
+		})
+	}
+}
+
+func TestMigrationWalksEveryKeySpace(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	stub.put(stub.composite("certificate_document", "c0"), CertificateDocument{ObjectType: "certificate_document", SchemaVersion: 1,
+		CertificateId: "c0", Document: "52998224725", DocumentType: "cpf"})
+	programKey := stub.composite("program", "u1", "p0")
+	stub.put(programKey, map[string]interface{}{"docType": "program", "schemaVersion": 1, "id": "p0", "universityId": "u1", "name": "History", "level": "bachelor"})
+	unitKey := stub.composite("unit", "u1", "x0")
+	stub.put(unitKey, map[string]interface{}{"docType": "unit", "schemaVersion": 9, "id": "x0"})
+	records := 0
+	for key := range stub.State {
+		if !strings.Contains(key, "~") {
+			records++ //indexes hold no records
+		}
+	}
+
+	//one record per batch, every record is scanned exactly once
+	scanned := map[string]int{}
+	var failed []string
+	for calls := 0; calls <= records; calls++ {
+		var result struct {
+			Batch  MigrationBatch  `json:"batch"`
+			Status MigrationStatus `json:"status"`
+		}
+		json.Unmarshal(ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "1")), &result)
+		scanned[result.Batch.Space+" "+result.Batch.StartKey]++
+		failed = append(failed, result.Batch.Failed...)
+		if result.Status.Done {
+			if result.Status.Scanned != records {
+				t.Fatalf("scanned %d of %d records", result.Status.Scanned, records)
+			}
+			break
+		}
+	}
+	for batch, times := range scanned {
+		if times > 1 {
+			t.Fatalf("batch %q ran %d times", batch, times)
+		}
+	}
+
+	program, err := get_program(stub, "u1", "p0")
+	if err != nil || program.SchemaVersion != ProgramSchemaVersion || program.Name != "History" {
+		t.Fatalf("program was not upgraded - %+v %v", program, err)
+	}
+	if len(failed) != 1 || !strings.HasPrefix(failed[0], unitKey+" - unknown schema version 9") {
+		t.Fatalf("failed %q", failed)
+	}
+}
+
+func TestMigrationReadsNumericV0Certificates(t *testing.T) {
+	//the JSON the first init_cert built by hand, document, body, city and date went in unquoted
+	v0 := func(id string, document string, body string, city string, date string) []byte {
+		return []byte(`{
+		"docType":"certificate",
+		"id": "` + id + `",
+		"name": "José",
+		"document": ` + document + `,
+		"body": ` + body + `,
+		"city": ` + city + `,
+		"date": ` + date + `,
+		"university": {
+			"id": "u1",
+			"dean": "Joao",
+			"name": "Universidade Um"
+		}
+	}`)
+	}
+	tests := []struct {
+		id       string
+		value    []byte
+		document string
+		body     string
+		city     string
+	}{
+		{"c0", v0("c0", "52998224725", "1", "3550308", "1501810298042"), "52998224725", "1", "3550308"},
+		{"c1", v0("c1", `"529.982.247-25"`, `"Bachelor"`, `"Sao Paulo"`, `"1501810298042"`), "52998224725", "Bachelor", "Sao Paulo"},
+		{"c2", v0("c2", "123456", "2.5", "-1", "1501810298042"), "123456", "2.5", "-1"},
+	}
+	stub := new_issuing_ledger(t)
+	for _, test := range tests {
+		stub.put(test.id, test.value)
+	}
+
+	var result struct {
+		Batch  MigrationBatch  `json:"batch"`
+		Status MigrationStatus `json:"status"`
+	}
+	json.Unmarshal(ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "100")), &result)
+	if len(result.Batch.Failed) > 0 {
+		t.Fatalf("failed %q", result.Batch.Failed)
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			certificate, err := get_certificate(stub, test.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if certificate.SchemaVersion != CertificateSchemaVersion || certificate.Document != test.document || certificate.Body != test.body ||
+				certificate.City != test.city || certificate.Date != "2017-08-04" || certificate.IssuedBy != "u1" {
+				t.Fatalf("certificate %+v", certificate)
+			}
+		})
+	}
+}
+
+func TestMigrateState(t *testing.T) {
+	stub := new_test_ledger()
+	//records as the first chaincode version stored them
+	stub.put("u0", map[string]interface{}{"docType": "university", "id": "u0", "dean": "Joao", "universityname": "Universidade Zero",
+		"document": "11.222.333/0001-81", "certifcates": []string{"c0"}})
+	stub.put("c0", map[string]interface{}{"docType": "certificate", "id": "c0", "name": "Ana", "document": "529.982.247-25", "body": "Bachelor",
+		"city": "Sao Paulo", "date": "2010-12-01", "university": map[string]string{"id": "u0", "dean": "Joao", "name": "Universidade Zero"}})
+
+	fails(t, stub.as("Org9MSP", "mallory").invoke("migrate_state", "100"), CodeUnauthorized)
+	var result struct {
+		Batch  MigrationBatch  `json:"batch"`
+		Status MigrationStatus `json:"status"`
+	}
+	json.Unmarshal(ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "100")), &result)
+	if !result.Status.Done || result.Status.Migrated != 2 || len(result.Batch.Failed) > 0 {
+		t.Fatalf("batch %+v, status %+v", result.Batch, result.Status)
+	}
+
+	certificate, err := get_certificate(stub, "c0")
+	if err != nil || certificate.SchemaVersion != CertificateSchemaVersion || certificate.IssuedBy != "u0" || certificate.DocumentType != "cpf" {
+		t.Fatalf("certificate %+v %v", certificate, err)
+	}
+	university, err := get_university(stub, "u0")
+	if err != nil || university.SchemaVersion != UniversitySchemaVersion || university.Status != "active" || len(university.Certificates) != 1 {
+		t.Fatalf("university %+v %v", university, err)
+	}
+
+	//a new pass finds nothing left to migrate
+	json.Unmarshal(ok(t, stub.as("Org1MSP", "admin").invoke("migrate_state", "100")), &result)
+	if !result.Status.Done || result.Status.Migrated != 0 {
+		t.Fatalf("status %+v", result.Status)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package openbadges maps achievements to Open Badges 3.0 credentials and back.
+//
+// An Open Badges 3.0 credential is a W3C Verifiable Credential, so building, signing and verifying go through
+// package vc, this package only knows the achievement vocabulary.
+package openbadges
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+const (
+	ContextV3      = "https://purl.imsglobal.org/spec/ob/v3p0/context-3.0.3.json"
+	TypeCredential = "OpenBadgeCredential"
+)
+
+// ----- Achievement ----- //
+type Achievement struct {
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Criteria        string `json:"criteria"`        //narrative of what earns it
+	AchievementType string `json:"achievementType"` //Open Badges achievement type, e.g. "Course", "Badge"
+	FieldOfStudy    string `json:"fieldOfStudy"`
+	CreditHours     int    `json:"creditHours"`
+}
+
+// ----- Subject ----- //
+type Subject struct {
+	Id          string      `json:"id"`   //recipient, may be empty
+	Name        string      `json:"name"` //recipient name
+	Achievement Achievement `json:"achievement"`
+}
+
+// achievement type of each non-degree program level
+var achievement_types = map[string]string{"extension": "Course", "workshop": "Badge", "short_course": "Course"}
+
+// ========================================================
+// Achievement Type - the Open Badges achievement type of a program level, "Achievement" when unknown
+// ========================================================
+func AchievementType(level string) string {
+	if achievementType, ok := achievement_types[level]; ok {
+		return achievementType
+	}
+	return "Achievement"
+}
+
+// ========================================================
+// New - an unsigned Open Badges 3.0 achievement credential
+// ========================================================
+func New(id string, issuer vc.Issuer, issued time.Time, subject Subject) vc.Credential {
+	issuer.Type = []string{"Profile"}
+	claims := map[string]interface{}{
+		"type": []string{"AchievementSubject"},
+		"name": subject.Name,
+		"achievement": map[string]interface{}{
+			"id":               subject.Achievement.Id,
+			"type":             []string{"Achievement"},
+			"name":             subject.Achievement.Name,
+			"description":      subject.Achievement.Description,
+			"criteria":         map[string]interface{}{"narrative": subject.Achievement.Criteria},
+			"achievementType":  subject.Achievement.AchievementType,
+			"fieldOfStudy":     subject.Achievement.FieldOfStudy,
+			"creditsAvailable": subject.Achievement.CreditHours,
+		},
+	}
+	if len(subject.Id) > 0 {
+		claims["id"] = subject.Id
+	}
+
+	credential := vc.New(id, []string{TypeCredential}, issuer, issued, claims)
+	credential.Context = append(credential.Context, ContextV3)
+	return credential
+}
+
+// ========================================================
+// Parse - decode and validate an Open Badges 3.0 credential, returning its subject
+// ========================================================
+func Parse(credentialAsBytes []byte) (vc.Credential, Subject, error) {
+	var subject Subject
+	credential, err := vc.Parse(credentialAsBytes)
+	if err != nil {
+		return credential, subject, err
+	}
+	subject, err = Validate(credential)
+	return credential, subject, err
+}
+
+// ========================================================
+// Validate - the credential has the Open Badges context, types and a complete achievement
+// ========================================================
+func Validate(credential vc.Credential) (Subject, error) {
+	var subject Subject
+	if !has(credential.Context, ContextV3) {
+		return subject, errors.New("openbadges: @context must include " + ContextV3)
+	}
+	if !has(credential.Type, TypeCredential) {
+		return subject, errors.New("openbadges: type must include " + TypeCredential)
+	}
+	if !has(credential.Issuer.Type, "Profile") {
+		return subject, errors.New("openbadges: issuer must be a Profile")
+	}
+
+	//the subject is a generic map in vc, round trip it through JSON to read it typed
+	var raw struct {
+		Id          string   `json:"id"`
+		Type        []string `json:"type"`
+		Name        string   `json:"name"`
+		Achievement struct {
+			Id          string   `json:"id"`
+			Type        []string `json:"type"`
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Criteria    struct {
+				Narrative string `json:"narrative"`
+			} `json:"criteria"`
+			AchievementType  string `json:"achievementType"`
+			FieldOfStudy     string `json:"fieldOfStudy"`
+			CreditsAvailable int    `json:"creditsAvailable"`
+		} `json:"achievement"`
+	}
+	subjectAsBytes, _ := json.Marshal(credential.CredentialSubject)
+	err := json.Unmarshal(subjectAsBytes, &raw)
+	if err != nil {
+		return subject, errors.New("openbadges: credentialSubject is not an AchievementSubject - " + err.Error())
+	}
+	if !has(raw.Type, "AchievementSubject") {
+		return subject, errors.New("openbadges: credentialSubject type must include AchievementSubject")
+	}
+	if !has(raw.Achievement.Type, "Achievement") || len(raw.Achievement.Id) == 0 || len(raw.Achievement.Name) == 0 {
+		return subject, errors.New("openbadges: achievement needs an id, a name and the Achievement type")
+	}
+
+	subject.Id = raw.Id
+	subject.Name = raw.Name
+	subject.Achievement = Achievement{
+		Id:              raw.Achievement.Id,
+		Name:            raw.Achievement.Name,
+		Description:     raw.Achievement.Description,
+		Criteria:        raw.Achievement.Criteria.Narrative,
+		AchievementType: raw.Achievement.AchievementType,
+		FieldOfStudy:    raw.Achievement.FieldOfStudy,
+		CreditHours:     raw.Achievement.CreditsAvailable,
+	}
+	return subject, nil
+}
+
+func has(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package openbadges
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jsilvaigor/AionChainCode/vc"
+)
+
+func test_subject() Subject {
+	return Subject{Id: "urn:aion:ch:graduate:g1", Name: "Maria", Achievement: Achievement{Id: "urn:aion:ch:program:u1/w1", Name: "Git",
+		Description: "Git workshop", Criteria: "Completed the 8 hour workshop Git", AchievementType: "Badge", FieldOfStudy: "Computing", CreditHours: 8}}
+}
+
+func TestNewAndValidate(t *testing.T) {
+	issued := time.Date(2017, 8, 4, 12, 0, 0, 0, time.UTC)
+	credential := New("urn:aion:ch:certificate:c1", vc.Issuer{Id: "urn:aion:ch:university:u1", Name: "Uni"}, issued, test_subject())
+	subject, err := Validate(credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != test_subject() {
+		t.Fatalf("subject %+v", subject)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *vc.Credential)
+	}{
+		{"no Open Badges context", func(c *vc.Credential) { c.Context = c.Context[:1] }},
+		{"no OpenBadgeCredential type", func(c *vc.Credential) { c.Type = []string{"VerifiableCredential"} }},
+		{"issuer is no Profile", func(c *vc.Credential) { c.Issuer.Type = nil }},
+		{"subject is no AchievementSubject", func(c *vc.Credential) { c.CredentialSubject["type"] = []string{"Person"} }},
+		{"achievement without a name", func(c *vc.Credential) {
+			c.CredentialSubject["achievement"].(map[string]interface{})["name"] = ""
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := New("urn:aion:ch:certificate:c1", vc.Issuer{Id: "urn:aion:ch:university:u1", Name: "Uni"}, issued, test_subject())
+			test.change(&changed)
+			if _, err := Validate(changed); err == nil {
+				t.Fatal("validated")
+			}
+		})
+	}
+}
+
+func TestAchievementType(t *testing.T) {
+	tests := map[string]string{"workshop": "Badge", "extension": "Course", "short_course": "Course", "bachelor": "Achievement", "": "Achievement"}
+	for level, achievementType := range tests {
+		if AchievementType(level) != achievementType {
+			t.Errorf("%q is %s, expected %s", level, AchievementType(level), achievementType)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ============================================================================================================================
+// Payload Definitions - functions take a single JSON document that is validated against a declared schema
+// ============================================================================================================================
+
+// ----- Field Spec ----- //
+type FieldSpec struct {
+	Name        string `json:"name"`                //json key of the field
+	Type        string `json:"type"`                //"string", "number", "boolean", "array" or "object"
+	Required    bool   `json:"required"`            //field must be present and non-empty
+	MaxLength   int    `json:"maxLength,omitempty"` //max length of a string field, 0 means unlimited
+	Description string `json:"description"`         //human readable description
+}
+
+// ----- Payload Schema ----- //
+type PayloadSchema struct {
+	Name          string      `json:"name"`                    //name of the payload
+	Fields        []FieldSpec `json:"fields"`                  //all known fields
+	Positional    []string    `json:"positional,omitempty"`    //legacy positional argument order (deprecated)
+	PositionalMin int         `json:"positionalMin,omitempty"` //fewest positional arguments taken, 0 for all, the fields left out are missing
+}
+
+// ----- Field Error ----- //
+type FieldError struct {
+	Field   string `json:"field"`   //json key of the offending field, "$" for the whole payload
+	Message string `json:"message"` //what is wrong with it
+}
+
+// ----- Validation Error ----- //
+type ValidationError struct {
+	Schema string       `json:"schema"`
+	Fields []FieldError `json:"fields"`
+}
+
+func (e ValidationError) Error() string {
+	errorAsBytes, _ := json.Marshal(e)
+	return string(errorAsBytes)
+}
+
+// ----- Certificate Payload ----- //
+type CertificatePayload struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Document      string `json:"document"`
+	DocumentType  string `json:"document_type"`
+	Body          string `json:"body"`
+	City          string `json:"city"`
+	Date          string `json:"date"`
+	UniversityId  string `json:"university_id"`
+	UniversityDoc string `json:"university_doc"`
+	DelegationId  string `json:"delegation_id"`
+	UnitId        string `json:"unit_id"`
+	ProgramId     string `json:"program_id"`
+}
+
+var certificate_schema = PayloadSchema{
+	Name: "certificate",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of certificate"},
+		{Name: "name", Type: "string", Required: true, MaxLength: 256, Description: "Name of the graduate"},
+		{Name: "document", Type: "string", Required: true, MaxLength: 32, Description: "Personal identification document, a CPF unless document_type says otherwise"},
+		{Name: "document_type", Type: "string", MaxLength: 16, Description: "\"cpf\" (default) or \"passport\" for foreign graduates"},
+		{Name: "body", Type: "string", Required: true, Description: "Principal content of certificate"},
+		{Name: "city", Type: "string", Required: true, MaxLength: 128, Description: "Emission city"},
+		{Name: "date", Type: "string", Required: true, MaxLength: 32, Description: "Conferral date, YYYY-MM-DD, ISO-8601 or 13 digit epoch milliseconds"},
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the issuing university"},
+		{Name: "university_doc", Type: "string", Required: true, MaxLength: 32, Description: "Document (cnpj) of the issuing university"},
+		{Name: "delegation_id", Type: "string", MaxLength: 64, Description: "Delegation used when a registrar submits instead of the dean"},
+		{Name: "unit_id", Type: "string", MaxLength: 64, Description: "Id of the issuing campus, faculty or department"},
+		{Name: "program_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the degree program, recognized at issuance"},
+	},
+	Positional:    []string{"id", "name", "document", "body", "city", "date", "university_id", "university_doc", "program_id"},
+	PositionalMin: 8, //the form before programs, program_id is then reported missing
+}
+
+// ----- University Payload ----- //
+type UniversityPayload struct {
+	Id           string `json:"id"`
+	Dean         string `json:"dean"`
+	DeanIdentity string `json:"dean_identity"`
+	Name         string `json:"name"`
+	Document     string `json:"document"`
+	Municipality string `json:"municipality"`
+}
+
+var university_schema = PayloadSchema{
+	Name: "university",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of university"},
+		{Name: "dean", Type: "string", Required: true, MaxLength: 256, Description: "Dean of university (reitor)"},
+		{Name: "dean_identity", Type: "string", MaxLength: 256, Description: "Identity the dean signs with, \"<msp>:<common name>\""},
+		{Name: "name", Type: "string", Required: true, MaxLength: 256, Description: "Name of university"},
+		{Name: "document", Type: "string", Required: true, MaxLength: 32, Description: "University national document, a valid CNPJ no other university has"},
+		{Name: "municipality", Type: "string", MaxLength: 128, Description: "Municipality of the seat of the university, required to export diplomas"},
+	},
+	Positional: []string{"id", "dean", "name", "document"},
+}
+
+// ============================================================================================================================
+// Parse Payload - read the function arguments into a typed payload
+//
+// Accepts either a single JSON document or, for schemas that declare one, the legacy positional form.
+// Every field is checked against the schema and all problems are reported at once as a ValidationError.
+//
+// Inputs - args of the invoked function, the schema and a pointer to the payload struct to fill
+// ============================================================================================================================
+func parse_payload(args []string, schema PayloadSchema, out interface{}) error {
+	var fields map[string]interface{}
+
+	if len(args) == 1 && strings.HasPrefix(strings.TrimSpace(args[0]), "{") {
+		decoded, err := decode_json_object(args[0])
+		if err != nil {
+			return ValidationError{Schema: schema.Name, Fields: []FieldError{{Field: "$", Message: err.Error()}}}
+		}
+		fields = decoded
+	} else if accepts_positional(schema, len(args)) {
+		fields = positional_to_fields(args, schema)
+	} else if len(schema.Positional) > 0 {
+		expected := strconv.Itoa(len(schema.Positional))
+		if schema.PositionalMin > 0 {
+			expected = strconv.Itoa(schema.PositionalMin) + " to " + expected
+		}
+		return new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1 JSON payload or "+expected+" positional arguments")
+	} else {
+		return new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1 JSON payload")
+	}
+
+	field_errors := validate_fields(fields, schema)
+	if len(field_errors) > 0 {
+		return ValidationError{Schema: schema.Name, Fields: field_errors}
+	}
+
+	//the fields are known to match the schema, let encoding/json fill in the typed payload
+	fieldsAsBytes, _ := json.Marshal(fields)
+	decoder := json.NewDecoder(bytes.NewReader(fieldsAsBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return ValidationError{Schema: schema.Name, Fields: []FieldError{{Field: "$", Message: err.Error()}}}
+	}
+	return nil
+}
+
+// ========================================================
+// Decode JSON Object - strict decoding of a single JSON object
+// ========================================================
+func decode_json_object(str string) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+
+	if !utf8.ValidString(str) {
+		return nil, errors.New("payload is not valid UTF-8")
+	}
+	decoder := json.NewDecoder(strings.NewReader(str))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, errors.New("payload is not a valid JSON object - " + err.Error())
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("payload has trailing data after the JSON object")
+	}
+	if fields == nil {
+		return nil, errors.New("payload must be a JSON object")
+	}
+	return fields, nil
+}
+
+// ========================================================
+// Accepts Positional - whether the schema takes this many legacy positional arguments
+// ========================================================
+func accepts_positional(schema PayloadSchema, count int) bool {
+	if len(schema.Positional) == 0 {
+		return false
+	}
+	if schema.PositionalMin > 0 {
+		return count >= schema.PositionalMin && count <= len(schema.Positional)
+	}
+	return count == len(schema.Positional)
+}
+
+// ========================================================
+// Positional To Fields - deprecated adapter for the old positional arguments
+// ========================================================
+func positional_to_fields(args []string, schema PayloadSchema) map[string]interface{} {
+	fmt.Println("DEPRECATED - positional arguments for '" + schema.Name + "', send a single JSON payload instead")
+	fields := make(map[string]interface{})
+	for i, value := range args {
+		fields[schema.Positional[i]] = value
+	}
+	return fields
+}
+
+// ========================================================
+// Validate Fields - check decoded fields against the schema, collecting every error
+// ========================================================
+func validate_fields(fields map[string]interface{}, schema PayloadSchema) []FieldError {
+	var field_errors []FieldError
+	known := make(map[string]FieldSpec)
+
+	for _, spec := range schema.Fields {
+		known[spec.Name] = spec
+		value, present := fields[spec.Name]
+		if !present || value == nil || value == "" {
+			if spec.Required {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		switch spec.Type {
+		case "number":
+			if _, ok := value.(json.Number); !ok {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be a number"})
+			}
+		case "boolean":
+			if _, ok := value.(bool); !ok {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be a boolean"})
+			}
+		case "array":
+			if _, ok := value.([]interface{}); !ok {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be an array"})
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); !ok {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be an object"})
+			}
+		default:
+			str, ok := value.(string)
+			if !ok {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be a string"})
+			} else if spec.MaxLength > 0 && utf8.RuneCountInString(str) > spec.MaxLength {
+				field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be at most " + strconv.Itoa(spec.MaxLength) + " characters"})
+			}
+		}
+	}
+
+	//unknown fields are rejected so a typo doesn't silently drop data
+	var unknown []string
+	for name := range fields {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		field_errors = append(field_errors, FieldError{Field: name, Message: "is not a known field"})
+	}
+
+	return field_errors
+}
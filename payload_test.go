@@ -0,0 +1,78 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		fields string //offending fields, "" when the payload parses
+		code   string
+	}{
+		{"json", []string{`{"id": "u1", "dean": "Joao", "name": "Uni", "document": "` + test_cnpj + `"}`}, "", ""},
+		{"positional", []string{"u1", "Joao", "Uni", test_cnpj}, "", ""},
+		{"too few positional", []string{"u1", "Joao", "Uni"}, "", CodeInvalidArgument},
+		{"no arguments", []string{}, "", CodeInvalidArgument},
+		{"not an object", []string{`{"id": }`}, "$", CodeInvalidArgument},
+		{"trailing data", []string{`{"id": "u1", "dean": "Joao", "name": "Uni", "document": "1"} {}`}, "$", CodeInvalidArgument},
+		{"not UTF-8", []string{"{\"id\": \"u\xff\"}"}, "$", CodeInvalidArgument},
+		{"missing fields", []string{`{"id": "u1"}`}, "dean,name,document", CodeInvalidArgument},
+		{"wrong type", []string{`{"id": 1, "dean": "Joao", "name": "Uni", "document": "1"}`}, "id", CodeInvalidArgument},
+		{"too long", []string{`{"id": "` + strings.Repeat("u", 65) + `", "dean": "Joao", "name": "Uni", "document": "1"}`}, "id", CodeInvalidArgument},
+		{"unknown fields", []string{`{"id": "u1", "dean": "Joao", "name": "Uni", "document": "1", "rector": "x", "cnpj": "y"}`}, "cnpj,rector", CodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var payload UniversityPayload
+			err := parse_payload(test.args, university_schema, &payload)
+			if test.code == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if payload.Id != "u1" || payload.Dean != "Joao" || payload.Document != test_cnpj {
+					t.Fatalf("payload %+v", payload)
+				}
+				return
+			}
+			chaincodeError := as_chaincode_error(err)
+			var fields []string
+			for _, field := range chaincodeError.Fields {
+				fields = append(fields, field.Field)
+			}
+			if chaincodeError.Code != test.code || strings.Join(fields, ",") != test.fields {
+				t.Fatalf("error %v", err)
+			}
+		})
+	}
+}
+
+func TestInvalidPayloadsReachTheClient(t *testing.T) {
+	stub := new_test_ledger()
+	ok(t, stub.as("Org1MSP", "admin").invoke("init_accreditor", `{"id": "a1", "name": "MEC", "mspId": "MECMSP"}`))
+	chaincodeError := fails(t, stub.as("MECMSP", "inspector").invoke("init_university", `{"id": "u1", "dean": 7}`), CodeInvalidArgument)
+	if len(chaincodeError.Fields) != 3 {
+		t.Fatalf("every problem is reported at once - %+v", chaincodeError)
+	}
+}
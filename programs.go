@@ -0,0 +1,357 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Program Payload ----- //
+type ProgramPayload struct {
+	Id                    string `json:"id"`
+	UniversityId          string `json:"university_id"`
+	Name                  string `json:"name"`
+	Level                 string `json:"level"`
+	FieldOfStudy          string `json:"field_of_study"`
+	CreditHours           int    `json:"credit_hours"`
+	EmecCode              string `json:"emec_code"`
+	RecognitionAct        string `json:"recognition_act"`
+	RecognitionValidFrom  string `json:"recognition_valid_from"`
+	RecognitionValidUntil string `json:"recognition_valid_until"`
+}
+
+var program_schema = PayloadSchema{
+	Name: "program",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of program"},
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university offering it"},
+		{Name: "name", Type: "string", Required: true, MaxLength: 256, Description: "Name of program"},
+		{Name: "level", Type: "string", Required: true, MaxLength: 32, Description: "Kind of degree, as listed in the accreditation scope, or a non-degree level"},
+		{Name: "field_of_study", Type: "string", Required: true, MaxLength: 256, Description: "Field of study"},
+		{Name: "credit_hours", Type: "number", Required: true, Description: "Total credit hours"},
+		{Name: "emec_code", Type: "string", MaxLength: 32, Description: "Official e-MEC code of the program, degree programs only"},
+		{Name: "recognition_act", Type: "string", MaxLength: 256, Description: "Official act (portaria) recognizing the program, degree programs only"},
+		{Name: "recognition_valid_from", Type: "string", MaxLength: 10, Description: "First valid day of the recognition, YYYY-MM-DD, degree programs only"},
+		{Name: "recognition_valid_until", Type: "string", MaxLength: 10, Description: "Last valid day of the recognition, YYYY-MM-DD, degree programs only"},
+	},
+}
+
+// ----- Recognition Payload ----- //
+type RecognitionPayload struct {
+	UniversityId string `json:"university_id"`
+	ProgramId    string `json:"program_id"`
+	Act          string `json:"act"`
+	ValidFrom    string `json:"valid_from"`
+	ValidUntil   string `json:"valid_until"`
+}
+
+var recognition_schema = PayloadSchema{
+	Name: "recognition",
+	Fields: []FieldSpec{
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university offering the program"},
+		{Name: "program_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the recognized program"},
+		{Name: "act", Type: "string", Required: true, MaxLength: 256, Description: "Official act (portaria) renewing the recognition"},
+		{Name: "valid_from", Type: "string", Required: true, MaxLength: 10, Description: "First valid day, YYYY-MM-DD"},
+		{Name: "valid_until", Type: "string", Required: true, MaxLength: 10, Description: "Last valid day, YYYY-MM-DD"},
+	},
+}
+
+// levels of extension courses and workshops, which need neither recognition nor accreditation
+var non_degree_levels = map[string]bool{"extension": true, "workshop": true, "short_course": true}
+
+// ========================================================
+// Get Program - get a program of a university from ledger
+// ========================================================
+func get_program(stub shim.ChaincodeStubInterface, university_id string, program_id string) (Program, error) {
+	var program Program
+	programKey, _ := stub.CreateCompositeKey("program", []string{university_id, program_id})
+	programAsBytes, err := stub.GetState(programKey)
+	if err != nil {
+		return program, errors.New("Failed to get program - " + program_id)
+	}
+	if programAsBytes == nil {
+		return program, new_error(CodeNotFound, "Program '"+program_id+"' does not exist in university '"+university_id+"'")
+	}
+	err = json.Unmarshal(programAsBytes, &program) //un stringify it aka JSON.parse()
+	if err != nil {
+		return program, errors.New("Program is not readable - " + program_id)
+	}
+	return program, nil
+}
+
+// ========================================================
+// Put Program - store a program
+// ========================================================
+func put_program(stub shim.ChaincodeStubInterface, program Program) error {
+	programKey, _ := stub.CreateCompositeKey("program", []string{program.UniversityId, program.Id})
+	return put_asset(stub, programKey, &program)
+}
+
+// ========================================================
+// Check Validity Days - both days are YYYY-MM-DD and the period is not reversed
+// ========================================================
+func check_validity_days(valid_from string, valid_until string, from_field string, until_field string) []FieldError {
+	var field_errors []FieldError
+	validFrom, err := parse_day(valid_from)
+	if err != nil {
+		field_errors = append(field_errors, FieldError{Field: from_field, Message: error_message(err)})
+	}
+	validUntil, err := parse_day(valid_until)
+	if err != nil {
+		field_errors = append(field_errors, FieldError{Field: until_field, Message: error_message(err)})
+	} else if validUntil.Before(validFrom) {
+		field_errors = append(field_errors, FieldError{Field: until_field, Message: "must not be before " + from_field})
+	}
+	return field_errors
+}
+
+// ========================================================
+// Check Program At - the program belongs to the university, is recognized and within the accreditation scope at the given time
+// ========================================================
+func check_program_at(stub shim.ChaincodeStubInterface, university_id string, program_id string, at time.Time) (Program, error) {
+	program, err := get_program(stub, university_id, program_id)
+	if err != nil || non_degree_levels[program.Level] {
+		return program, err
+	}
+	validFrom, err := parse_day(program.RecognitionValidFrom)
+	if err != nil {
+		return program, err
+	}
+	validUntil, err := parse_day(program.RecognitionValidUntil)
+	if err != nil {
+		return program, err
+	}
+	if at.Before(validFrom) {
+		return program, new_error(CodeConflict, "Recognition of program '"+program_id+"' is only valid from "+program.RecognitionValidFrom)
+	}
+	if !at.Before(validUntil.AddDate(0, 0, 1)) {
+		return program, new_error(CodeConflict, "Recognition of program '"+program_id+"' expired on "+program.RecognitionValidUntil)
+	}
+
+	accreditation, err := get_accreditation(stub, university_id)
+	if err != nil {
+		return program, err
+	}
+	if !contains(accreditation.Scope, program.Level) {
+		return program, new_error(CodeConflict, "University '"+university_id+"' is not accredited to confer "+program.Level+" degrees")
+	}
+	return program, nil
+}
+
+// ============================================================================================================================
+// Init Program - register a recognized degree program of a university, accreditor of record only
+//
+// Non-degree programs (extension, workshop, short_course) are registered by the dean and skip recognition.
+//
+// Inputs - JSON payload validated against program_schema
+//
+//	{
+//		"id": "p123",
+//		"university_id": "u123",
+//		"name": "Engenharia de Computação",
+//		"level": "bachelor",
+//		"field_of_study": "Computer Engineering",
+//		"credit_hours": 3600,
+//		"emec_code": "1234567",
+//		"recognition_act": "Portaria SERES 123/2016",
+//		"recognition_valid_from": "2016-03-01",
+//		"recognition_valid_until": "2021-02-28"
+//	}
+//
+// ============================================================================================================================
+func init_program(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting init_program")
+
+	//input sanitation
+	var payload ProgramPayload
+	err = parse_payload(args, program_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	var field_errors []FieldError
+	degree := !non_degree_levels[payload.Level]
+	if degree {
+		if len(payload.EmecCode) == 0 {
+			field_errors = append(field_errors, FieldError{Field: "emec_code", Message: "is required for degree programs"})
+		}
+		if len(payload.RecognitionAct) == 0 {
+			field_errors = append(field_errors, FieldError{Field: "recognition_act", Message: "is required for degree programs"})
+		}
+		field_errors = append(field_errors, check_validity_days(payload.RecognitionValidFrom, payload.RecognitionValidUntil, "recognition_valid_from", "recognition_valid_until")...)
+	}
+	if payload.CreditHours <= 0 {
+		field_errors = append(field_errors, FieldError{Field: "credit_hours", Message: "must be positive"})
+	}
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: program_schema.Name, Fields: field_errors})
+	}
+
+	university, err := get_university(stub, payload.UniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	if degree {
+		//recognition is granted by the accreditor of the university
+		_, err = require_accreditor_of(stub, university.Id)
+		if err != nil {
+			return error_response(err)
+		}
+
+		//the university must be accredited for this kind of degree
+		accreditation, err := get_accreditation(stub, university.Id)
+		if err != nil {
+			return error_response(err)
+		}
+		if !contains(accreditation.Scope, payload.Level) {
+			return error_response(new_error(CodeUnauthorized, "University '"+university.Id+"' is not accredited to confer "+payload.Level+" degrees"))
+		}
+	} else {
+		_, err = require_signer(stub, university)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+
+	//check if program already exists
+	_, err = get_program(stub, payload.UniversityId, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This program already exists - "+payload.Id))
+	}
+
+	var program Program
+	program.ObjectType = "program"
+	program.SchemaVersion = ProgramSchemaVersion
+	program.Id = payload.Id
+	program.UniversityId = payload.UniversityId
+	program.Name = payload.Name
+	program.Level = payload.Level
+	program.FieldOfStudy = payload.FieldOfStudy
+	program.CreditHours = payload.CreditHours
+	program.EmecCode = payload.EmecCode
+	program.RecognitionAct = payload.RecognitionAct
+	program.RecognitionValidFrom = payload.RecognitionValidFrom
+	program.RecognitionValidUntil = payload.RecognitionValidUntil
+
+	err = put_program(stub, program)
+	if err != nil {
+		fmt.Println("Could not store program")
+		return error_response(err)
+	}
+
+	fmt.Println("- end init_program")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Recognize Program - renew the recognition of a program, accreditor of record only
+//
+// Inputs - JSON payload validated against recognition_schema
+//
+//	{
+//		"university_id": "u123",
+//		"program_id": "p123",
+//		"act": "Portaria SERES 456/2021",
+//		"valid_from": "2021-03-01",
+//		"valid_until": "2026-02-28"
+//	}
+//
+// ============================================================================================================================
+func recognize_program(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting recognize_program")
+
+	//input sanitation
+	var payload RecognitionPayload
+	err = parse_payload(args, recognition_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	field_errors := check_validity_days(payload.ValidFrom, payload.ValidUntil, "valid_from", "valid_until")
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: recognition_schema.Name, Fields: field_errors})
+	}
+
+	_, err = require_accreditor_of(stub, payload.UniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	program, err := get_program(stub, payload.UniversityId, payload.ProgramId)
+	if err != nil {
+		return error_response(err)
+	}
+	if non_degree_levels[program.Level] {
+		return error_response(new_error(CodeInvalidArgument, "Program '"+program.Id+"' is a "+program.Level+" and needs no recognition"))
+	}
+
+	program.RecognitionAct = payload.Act
+	program.RecognitionValidFrom = payload.ValidFrom
+	program.RecognitionValidUntil = payload.ValidUntil
+	err = put_program(stub, program)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end recognize_program")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Programs - list all programs of a university
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_programs(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var programs []Program
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("program", []string{args[0]})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var program Program
+		json.Unmarshal(record.Value, &program) //un stringify it aka JSON.parse()
+		programs = append(programs, program)
+	}
+
+	programsAsBytes, _ := json.Marshal(programs) //convert to array of bytes
+	return shim.Success(programsAsBytes)
+}
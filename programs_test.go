@@ -0,0 +1,84 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// program - an init_program payload of u1 with its level and recognition validity
+func program(id string, level string, valid_until string) string {
+	return `{"id": "` + id + `", "university_id": "u1", "name": "Program ` + id + `", "level": "` + level + `", "field_of_study": "Computing", "credit_hours": 3200,
+		"emec_code": "99", "recognition_act": "Portaria 5", "recognition_valid_from": "2000-01-01", "recognition_valid_until": "` + valid_until + `"}`
+}
+
+func TestInitProgram(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	tests := []struct {
+		name    string
+		caller  string
+		payload string
+		code    string
+	}{
+		{"degree by the dean", "Org1MSP:joao", program("p2", "bachelor", "2099-12-31"), CodeUnauthorized},
+		{"degree by the accreditor", "MECMSP:inspector", program("p2", "bachelor", "2099-12-31"), ""},
+		{"taken id", "MECMSP:inspector", program("p2", "bachelor", "2099-12-31"), CodeAlreadyExists},
+		{"degree outside the accreditation scope", "MECMSP:inspector", program("p3", "master", "2099-12-31"), CodeUnauthorized},
+		{"degree without e-MEC code and act", "MECMSP:inspector", `{"id": "p3", "university_id": "u1", "name": "History", "level": "bachelor", "field_of_study": "History", "credit_hours": 2800}`, CodeInvalidArgument},
+		{"recognition ending before it starts", "MECMSP:inspector", program("p3", "bachelor", "1999-12-31"), CodeInvalidArgument},
+		{"no credit hours", "MECMSP:inspector", `{"id": "p3", "university_id": "u1", "name": "History", "level": "workshop", "field_of_study": "History", "credit_hours": 0}`, CodeInvalidArgument},
+		{"workshop by the accreditor", "MECMSP:inspector", `{"id": "w1", "university_id": "u1", "name": "Git", "level": "workshop", "field_of_study": "Computing", "credit_hours": 8}`, CodeUnauthorized},
+		{"workshop by the dean", "Org1MSP:joao", `{"id": "w1", "university_id": "u1", "name": "Git", "level": "workshop", "field_of_study": "Computing", "credit_hours": 8}`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).invoke("init_program", test.payload)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	var programs []Program
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("read_programs", "u1")), &programs)
+	if len(programs) != 3 {
+		t.Fatalf("programs %+v", programs)
+	}
+}
+
+func TestProgramRecognitionGatesIssuance(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.as("MECMSP", "inspector").invoke("init_program", program("p2", "bachelor", "2017-07-31")))
+	ok(t, stub.as("Org1MSP", "joao").invoke("init_program", `{"id": "w1", "university_id": "u1", "name": "Git", "level": "workshop", "field_of_study": "Computing", "credit_hours": 8}`))
+
+	fails(t, stub.issue(map[string]interface{}{"id": "c1", "program_id": "p2"}), CodeConflict)
+	fails(t, stub.issue(map[string]interface{}{"id": "c1", "program_id": "p9"}), CodeNotFound)
+	ok(t, stub.issue(map[string]interface{}{"id": "c1", "program_id": "w1"}))
+
+	renewal := `{"university_id": "u1", "program_id": "p2", "act": "Portaria 6", "valid_from": "2017-08-01", "valid_until": "2022-07-31"}`
+	fails(t, stub.as("Org1MSP", "joao").invoke("recognize_program", renewal), CodeUnauthorized)
+	fails(t, stub.as("MECMSP", "inspector").invoke("recognize_program", `{"university_id": "u1", "program_id": "w1", "act": "Portaria 6", "valid_from": "2017-08-01", "valid_until": "2022-07-31"}`), CodeInvalidArgument)
+	ok(t, stub.as("MECMSP", "inspector").invoke("recognize_program", renewal))
+	ok(t, stub.issue(map[string]interface{}{"id": "c2", "program_id": "p2"}))
+}
@@ -0,0 +1,92 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// every asset embeds Provenance, which gives it this method
+type asset interface {
+	set_provenance(createdBy *WriteStamp, stamp WriteStamp)
+}
+
+func (p *Provenance) set_provenance(createdBy *WriteStamp, stamp WriteStamp) {
+	p.CreatedBy = createdBy
+	p.UpdatedBy = &stamp
+}
+
+// ========================================================
+// Get Write Stamp - who is writing, in which transaction, when and on which channel
+// ========================================================
+func get_write_stamp(stub shim.ChaincodeStubInterface) (WriteStamp, error) {
+	var stamp WriteStamp
+	identity, err := get_creator(stub)
+	if err != nil {
+		return stamp, err
+	}
+	now, err := get_tx_time(stub)
+	if err != nil {
+		return stamp, err
+	}
+	stamp.Identity = identity.Id
+	stamp.MspId = identity.MspId
+	stamp.Fingerprint = identity.Fingerprint
+	stamp.TxId = stub.GetTxID()
+	stamp.Timestamp = now.Format(time.RFC3339Nano)
+	stamp.ChannelId = stub.GetChannelID()
+	return stamp, nil
+}
+
+// ========================================================
+// Put Asset - stamp an asset with the provenance of this transaction and store it, every asset write goes through here
+//
+// The asset is created when the key holds nothing yet. An update keeps the createdBy stored under the key, whatever
+// the value carries, so writers may build the value from scratch. Assets written before provenance was recorded
+// only get updatedBy, the history of the key on the peer holds their creator.
+// ========================================================
+func put_asset(stub shim.ChaincodeStubInterface, key string, value asset) error {
+	stamp, err := get_write_stamp(stub)
+	if err != nil {
+		return err
+	}
+	existingAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return errors.New("Failed to get state for " + key)
+	}
+	created := stamp
+	createdBy := &created
+	if existingAsBytes != nil {
+		var stored Provenance
+		json.Unmarshal(existingAsBytes, &stored) //unreadable or raw values have no creator to keep
+		createdBy = stored.CreatedBy
+	}
+	value.set_provenance(createdBy, stamp)
+
+	valueAsBytes, err := json.Marshal(value) //convert to array of bytes
+	if err != nil {
+		return errors.New("Could not encode " + key + " - " + err.Error())
+	}
+	return stub.PutState(key, valueAsBytes)
+}
@@ -0,0 +1,155 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestProvenanceOnCreation(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	university, _ := get_university(stub, "u1")
+	accreditation, _ := get_accreditation(stub, "u1")
+	program, _ := get_program(stub, "u1", "p1")
+	ok(t, stub.issue(nil))
+	issuedIn := "tx" + strconv.Itoa(stub.txs)
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_transcript", `{"certificate_id": "c1", "courses": `+test_courses+`}`))
+
+	certificate, _ := get_certificate(stub, "c1")
+	transcript, _ := get_transcript(stub, "c1")
+	tests := []struct {
+		name       string
+		provenance Provenance
+		identity   string
+	}{
+		{"university", university.Provenance, "MECMSP:inspector"},
+		{"accreditation", accreditation.Provenance, "MECMSP:inspector"},
+		{"program", program.Provenance, "MECMSP:inspector"},
+		{"certificate", certificate.Provenance, "Org1MSP:joao"},
+		{"transcript", transcript.Provenance, "Org1MSP:joao"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			created, updated := test.provenance.CreatedBy, test.provenance.UpdatedBy
+			if created == nil || updated == nil || *created != *updated {
+				t.Fatalf("provenance %+v %+v, a new asset is created and updated by the same write", created, updated)
+			}
+			mspId, _ := split_identity(test.identity)
+			if created.Identity != test.identity || created.MspId != mspId || len(created.Fingerprint) != 64 || len(created.TxId) == 0 || len(created.Timestamp) == 0 {
+				t.Fatalf("write stamp %+v", created)
+			}
+		})
+	}
+	if certificate.CreatedBy.TxId != issuedIn || certificate.CreatedBy.Timestamp != "2017-08-04T12:05:00Z" {
+		t.Fatalf("certificate written in %s at %s", certificate.CreatedBy.TxId, certificate.CreatedBy.Timestamp)
+	}
+}
+
+func TestProvenanceOnUpdate(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	before, _ := get_university(stub, "u1")
+	//issuing adds the certificate to the university
+	ok(t, stub.issue(nil))
+	updatedIn := "tx" + strconv.Itoa(stub.txs)
+
+	university, _ := get_university(stub, "u1")
+	if university.CreatedBy == nil || *university.CreatedBy != *before.CreatedBy {
+		t.Fatalf("creation stamp changed from %+v to %+v", before.CreatedBy, university.CreatedBy)
+	}
+	if university.UpdatedBy.Identity != "Org1MSP:joao" || university.UpdatedBy.TxId != updatedIn {
+		t.Fatalf("update stamp %+v", university.UpdatedBy)
+	}
+
+	//a university written before provenance was recorded has no creation stamp to keep
+	universityAsBytes, _ := stub.GetState("u1")
+	var fields map[string]interface{}
+	json.Unmarshal(universityAsBytes, &fields)
+	delete(fields, "createdBy")
+	delete(fields, "updatedBy")
+	stub.put("u1", fields)
+	ok(t, stub.issue(map[string]interface{}{"id": "c2"}))
+	university, _ = get_university(stub, "u1")
+	if university.CreatedBy != nil || university.UpdatedBy == nil || university.UpdatedBy.Identity != "Org1MSP:joao" {
+		t.Fatalf("provenance %+v %+v", university.CreatedBy, university.UpdatedBy)
+	}
+}
+
+func TestUpdatesKeepTheCreationStamp(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(t testing.TB, stub *test_stub)
+		read   func(t testing.TB, stub *test_stub) Provenance
+	}{
+		{"university status", func(t testing.TB, stub *test_stub) {
+			ok(t, stub.as("MECMSP", "inspector").invoke("suspend_university", "u1", "Supervision 1"))
+		}, func(t testing.TB, stub *test_stub) Provenance {
+			university, _ := get_university(stub, "u1")
+			return university.Provenance
+		}},
+		{"accreditation status", func(t testing.TB, stub *test_stub) {
+			ok(t, stub.as("MECMSP", "inspector").invoke("set_accreditation_status", "u1", "suspended", "Supervision 1"))
+		}, func(t testing.TB, stub *test_stub) Provenance {
+			accreditation, _ := get_accreditation(stub, "u1")
+			return accreditation.Provenance
+		}},
+		{"ended dean term", func(t testing.TB, stub *test_stub) {
+			ok(t, stub.as("Org1MSP", "joao").invoke("set_dean", "u1", "Joao", "Jose", "Org1MSP:jose"))
+		}, func(t testing.TB, stub *test_stub) Provenance {
+			var terms []DeanTerm
+			json.Unmarshal(ok(t, stub.invoke("read_dean_terms", "u1")), &terms)
+			for _, term := range terms {
+				if term.Identity == "Org1MSP:joao" {
+					return term.Provenance
+				}
+			}
+			return Provenance{}
+		}},
+		{"value built from scratch", func(t testing.TB, stub *test_stub) {
+			stub.begin()
+			defer stub.end()
+			university, _ := get_university(stub, "u1")
+			university.Provenance = Provenance{}
+			if err := put_asset(stub, "u1", &university); err != nil {
+				t.Fatal(err)
+			}
+		}, func(t testing.TB, stub *test_stub) Provenance {
+			university, _ := get_university(stub, "u1")
+			return university.Provenance
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := new_issuing_ledger(t)
+			before := test.read(t, stub)
+			test.update(t, stub)
+			updatedIn := "tx" + strconv.Itoa(stub.txs)
+
+			after := test.read(t, stub)
+			if before.CreatedBy == nil || after.CreatedBy == nil || *after.CreatedBy != *before.CreatedBy {
+				t.Fatalf("creation stamp changed from %+v to %+v", before.CreatedBy, after.CreatedBy)
+			}
+			if after.UpdatedBy == nil || after.UpdatedBy.TxId != updatedIn {
+				t.Fatalf("update stamp %+v, expected one from %s", after.UpdatedBy, updatedIn)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -30,35 +31,38 @@ import (
 // ============================================================================================================================
 // Read - read a generic variable from ledger
 //
-// Shows Off GetState() - reading a key/value from the ledger
-//
 // Inputs - Array of strings
-//  0
-//  key
-//  "abc"
-// 
+//
+//	0
+//	key
+//	"abc"
+//
+// Graduates, transcripts, credit recognitions and consents are personal data and only readable through their own functions.
+//
 // Returns - string
 // ============================================================================================================================
 func read(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var key, jsonResp string
+	var key string
 	var err error
 	fmt.Println("starting read")
 
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting key of the var to query")
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting key of the var to query"))
 	}
 
 	// input sanitation
 	err = sanitize_arguments(args)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
 	key = args[0]
+	if objectType := restricted_object_type(key); len(objectType) > 0 {
+		return error_response(new_error(CodeUnauthorized, "'"+objectType+"' records are restricted, use their read functions"))
+	}
 	valAsbytes, err := stub.GetState(key) //get the var from ledger
 	if err != nil {
-		jsonResp = "{\"Error\":\"Failed to get state for " + key + "\"}"
-		return shim.Error(jsonResp)
+		return error_response(new_error(CodeInternal, "Failed to get state for "+key))
 	}
 
 	fmt.Println("- end read")
@@ -66,41 +70,32 @@ func read(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 }
 
 // ============================================================================================================================
-// Get all certificates from university
+// Get all certificates from university, admins, accreditors and staff of the university only
 //
 // Inputs - university_id
 //
-// ----- Marbles ----- //
-//type Certificate struct {
-//	ObjectType string `json:"docType"`                //field for couchdb
-//	Id         string `json:"id"`                     //UUID of certificate
-//	Name       string `json:"name"`                   //Name of the graduate
-//	Document   string `json:"document"`               //Personal identification document (cpf)
-//	Body       string `json:"body"`                   //Principal content of certificate
-//	City       string `json:"city"`                   //Emission city
-//	Date       string `json:"date"`                   //Emission timestamp
-//	University UniversityRelation `json:"university"` //University
-//}
 // Returns:
-// {
-//	"certificates": [{
-//			"id": "c123",
-//			"name": "Jose"
-//			"document": "123"
-//			"body": "certificate..."
-//			"city": "Sao Paulo"
-//			"date": "1501813348098"
-//			"university": {
-//			    "id": "u213",
-//			    "name": "UniUni",
-//			    "dean": "Joao"
-//          }
-//	}]
-// }
+//
+//	{
+//		"certificates": [{
+//				"id": "c123",
+//				"name": "Jose"
+//				"document": "123"
+//				"body": "certificate..."
+//				"city": "Sao Paulo"
+//				"date": "1501813348098"
+//				"university": {
+//				    "id": "u213",
+//				    "name": "UniUni",
+//				    "dean": "Joao"
+//	         }
+//		}]
+//	}
+//
 // ============================================================================================================================
 func read_all_certificates_from_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	type Everything struct {
-		Certificates []Certificate   `json:"certificates"`
+		Certificates []Certificate `json:"certificates"`
 	}
 	var everything Everything
 
@@ -108,26 +103,30 @@ func read_all_certificates_from_university(stub shim.ChaincodeStubInterface, arg
 	fmt.Println("starting read_all_certificates_from_university")
 
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting key of the var to query")
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting key of the var to query"))
 	}
 
 	// input sanitation
 	err = sanitize_arguments(args)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
 	// get university
 	university, err := get_university(stub, args[0])
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
+	}
+	err = require_university_reader(stub, university)
+	if err != nil {
+		return error_response(err)
 	}
 
 	if len(university.Certificates) > 0 {
 		for _, item := range university.Certificates {
 			certificate, err := get_certificate(stub, item)
 			if err != nil {
-				return shim.Error("An error occurred while retrieving certificates")
+				return error_response(new_error(CodeInternal, "An error occurred while retrieving certificates"))
 			}
 			everything.Certificates = append(everything.Certificates, certificate)
 		}
@@ -143,57 +142,76 @@ func read_all_certificates_from_university(stub shim.ChaincodeStubInterface, arg
 // ============================================================================================================================
 // Get history of university
 //
-// Shows Off GetHistoryForKey() - reading complete history of a key/value
-//
 // Inputs - Array of strings
-//  0
-//  id
-//  "m01490985296352SjAyM"
+//
+//	0
+//	id
+//	"m01490985296352SjAyM"
+//
+// Only universities have a public history, composite keys and records of any other docType are refused.
 // ============================================================================================================================
 func getHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	type AuditHistory struct {
-		TxId      string   `json:"txId"`
-		Value     University   `json:"value"`
-		Timestamp int64   `json:"timestamp"`
+		TxId      string     `json:"txId"`
+		Value     University `json:"value"`
+		Timestamp int64      `json:"timestamp"`
 	}
 	var history []AuditHistory
 	var err error
 	var university University
 
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
 	}
 
 	university_id := args[0]
 	fmt.Printf("- start getHistoryForUniversity: %s\n", university_id)
 
+	//graduates, transcripts and every other composite record keep their history to themselves
+	if objectType := restricted_object_type(university_id); len(objectType) > 0 {
+		return error_response(new_error(CodeUnauthorized, "'"+objectType+"' records are restricted, use their read functions"))
+	}
+	if strings.HasPrefix(university_id, "\x00") {
+		return error_response(new_error(CodeInvalidArgument, "Expecting a university id, got a composite key"))
+	}
+	valAsBytes, err := stub.GetState(university_id)
+	if err != nil {
+		return error_response(new_error(CodeInternal, "Failed to get state for "+university_id))
+	}
+	var header struct {
+		ObjectType string `json:"docType"`
+	}
+	json.Unmarshal(valAsBytes, &header) //unreadable or missing values have no docType either
+	if header.ObjectType != "university" {
+		return error_response(new_error(CodeNotFound, "University does not exist - "+university_id))
+	}
+
 	// Get History
 	resultsIterator, err := stub.GetHistoryForKey(university_id)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 	defer resultsIterator.Close()
 
 	for resultsIterator.HasNext() {
 		historicValue, err := resultsIterator.Next()
 		if err != nil {
-			return shim.Error(err.Error())
+			return error_response(err)
 		}
 
 		var tx AuditHistory
-		tx.TxId = historicValue.TxId                     //copy transaction id over
-		json.Unmarshal(historicValue.Value, &university) //un stringify it aka JSON.parse()
-		if historicValue == nil { //university has been deleted
+		tx.TxId = historicValue.TxId    //copy transaction id over
+		if historicValue.Value == nil { //university has been deleted
 			var emptyUniversity University
 			tx.Value = emptyUniversity //copy nil university
 		} else {
-			json.Unmarshal(historicValue.Value, &university) //un stringify it aka JSON.parse()
-			tx.Value = university                            //copy university over
+			university, _ = decode_university(historicValue.Value) //un stringify it, upgrading older schema versions
+			tx.Value = university                                  //copy university over
 		}
 		tx.Timestamp = historicValue.Timestamp.Seconds
 		history = append(history, tx) //add this tx to the list
 	}
-	fmt.Printf("- getHistoryForUniversity returning:\n%s", university)
+	fmt.Printf("- getHistoryForUniversity returning:\n%v", university)
 
 	//change to array of bytes
 	historyAsBytes, _ := json.Marshal(history) //convert to array of bytes
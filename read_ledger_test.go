@@ -0,0 +1,91 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReadPlainAndRestrictedKeys(t *testing.T) {
+	stub := new_test_stub()
+	stub.put("u123", map[string]string{"docType": "university", "id": "u123"})
+	stub.put(stub.composite("graduate", "g1"), map[string]string{"docType": "graduate", "id": "g1"})
+	stub.put(stub.composite("transcript", "c123"), map[string]string{"docType": "transcript"})
+	stub.put(stub.composite("dean_term", "u123", "t1"), map[string]string{"docType": "dean_term"})
+
+	tests := []struct {
+		name string
+		key  string
+		code string
+	}{
+		{"plain key", "u123", ""},
+		{"missing plain key", "nope", ""},
+		{"empty looking key", "x", ""},
+		{"public composite key", stub.composite("dean_term", "u123", "t1"), ""},
+		{"graduate", stub.composite("graduate", "g1"), CodeUnauthorized},
+		{"transcript", stub.composite("transcript", "c123"), CodeUnauthorized},
+		{"graduate prefix only", stub.composite("graduate"), CodeUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.invoke("read", test.key)
+			if test.code == "" {
+				ok(t, res)
+			} else {
+				fails(t, res, test.code)
+			}
+		})
+	}
+}
+
+func TestUniversityHistoryReadsUniversitiesOnly(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+
+	tests := []struct {
+		name string
+		key  string
+		code string
+	}{
+		{"graduate", stub.composite("graduate", "g1"), CodeUnauthorized},
+		{"transcript", stub.composite("transcript", "c1"), CodeUnauthorized},
+		{"public composite key", stub.composite("dean_term", "u1", "t1"), CodeInvalidArgument},
+		{"certificate", "c1", CodeNotFound},
+		{"missing university", "u9", CodeNotFound},
+		{"university", "u1", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.as("Org9MSP", "anyone").invoke("getUniversityHistory", test.key)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			var history []struct {
+				Value University `json:"value"`
+			}
+			json.Unmarshal(ok(t, res), &history)
+			if len(history) == 0 || history[len(history)-1].Value.Id != test.key {
+				t.Fatalf("history %+v", history)
+			}
+		})
+	}
+}
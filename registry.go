@@ -0,0 +1,593 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Roles - who may call a function, any one of the roles a handler lists is enough
+//
+// The dispatcher checks them before running the function. Roles tied to a university (dean, registrar, accreditor
+// of record) or a graduate are checked against what the scope of the handler resolves, functions may check further.
+// ============================================================================================================================
+const (
+	RoleAnyone             = "anyone"               //no identity check
+	RoleAdmin              = "admin"                //identity listed in the admins of the config
+	RoleRegulator          = "regulator"            //accreditor of the regulator MSP of the config
+	RoleAccreditor         = "accreditor"           //identity acting for a registered accreditor
+	RoleAccreditorOfRecord = "accreditor_of_record" //accreditor that accredited the university
+	RoleDean               = "dean"                 //dean in office of the university
+	RoleRegistrar          = "registrar"            //registrar holding an active delegation from the dean
+	RoleMember             = "consortium_member"    //identity of a governance member MSP
+	RoleGraduate           = "graduate"             //caller proving the graduate document in the transient map
+)
+
+var role_descriptions = map[string]string{
+	RoleAnyone:             "No identity check",
+	RoleAdmin:              "Identity listed in the admins of the chaincode config",
+	RoleRegulator:          "Accreditor of the regulator MSP of the chaincode config",
+	RoleAccreditor:         "Identity acting for a registered accreditor",
+	RoleAccreditorOfRecord: "Accreditor that accredited the university the function acts on",
+	RoleDean:               "Dean in office of the university the function acts on",
+	RoleRegistrar:          "Registrar holding an active delegation from the dean in office",
+	RoleMember:             "Identity of a governance member MSP",
+	RoleGraduate:           "Caller proving the graduate document of the certificates with cpf or passport in the transient map",
+}
+
+var error_codes = []string{CodeNotFound, CodeAlreadyExists, CodeUnauthorized, CodeInvalidArgument, CodeConflict, CodeInternal}
+
+// ----- Handler ----- //
+// a function of the chaincode, Invoke dispatches through the registry and describe publishes it
+type Handler struct {
+	Name        string         `json:"name"`                //function name clients invoke
+	Description string         `json:"description"`         //human readable description
+	Args        []FieldSpec    `json:"args,omitempty"`      //positional arguments, in order
+	Payload     *PayloadSchema `json:"payload,omitempty"`   //schema of the single JSON payload argument
+	Transient   []FieldSpec    `json:"transient,omitempty"` //keys read from the transient map
+	Roles       []string       `json:"roles"`               //who may call it, see the roles above
+	Scope       *Scope         `json:"scope,omitempty"`     //where the university or graduate the call acts on comes from
+	ReadOnly    bool           `json:"readOnly"`            //never writes the ledger, evaluate it as a query
+	run         func(shim.ChaincodeStubInterface, []string) pb.Response
+}
+
+// ----- Catalog ----- //
+// everything describe returns
+type Catalog struct {
+	Functions  []Handler         `json:"functions"`  //every function, in registry order
+	Roles      map[string]string `json:"roles"`      //role names used by the functions and what they mean
+	ErrorCodes []string          `json:"errorCodes"` //codes a failing function may return
+}
+
+// ----- Scope ----- //
+// how a handler finds the universities and graduate its call acts on
+type Scope struct {
+	From    string                                                      `json:"from"` //argument or payload field the scope is read from
+	resolve func(shim.ChaincodeStubInterface, []string) (Target, error) //
+}
+
+// ----- Target ----- //
+// what a call acts on, university and graduate bound roles are checked against it
+type Target struct {
+	Universities []string //ids of the universities
+	Certificates []string //ids of the certificates of the graduate the call acts on, the graduate proves one of their documents
+}
+
+// argument and transient specs shared by many handlers
+var university_id_arg = FieldSpec{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university"}
+var certificate_id_arg = FieldSpec{Name: "certificate_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the certificate"}
+var graduate_id_arg = FieldSpec{Name: "graduate_id", Type: "string", Required: true, MaxLength: 64, Description: "Pseudonymous id of the graduate"}
+var reason_arg = FieldSpec{Name: "reason", Type: "string", Required: true, Description: "Why, recorded with the change"}
+
+var graduate_key_transient = FieldSpec{Name: "graduate_key", Type: "string", Description: "Key graduate ids are derived with, must match the digest in the config"}
+var graduate_reader_transient = []FieldSpec{
+	{Name: "cpf", Type: "string", Description: "CPF of the graduate, when the caller proves the document"},
+	{Name: "passport", Type: "string", Description: "Passport number of a foreign graduate, instead of cpf"},
+}
+
+var graduate_readers = []string{RoleAdmin, RoleAccreditor, RoleDean, RoleRegistrar, RoleGraduate}
+var university_readers = []string{RoleAdmin, RoleAccreditor, RoleDean, RoleRegistrar}
+var issuers = []string{RoleDean, RoleRegistrar}
+var supervisors = []string{RoleAccreditorOfRecord, RoleRegulator}
+
+// every function of the chaincode, filled in init since describe reads the registry itself
+var handlers []Handler
+
+func init() {
+	handlers = []Handler{
+		// ---- config ---- //
+		{Name: "update_config", Description: "Replace the chaincode config, refused while governance is on", Payload: &config_schema, Roles: []string{RoleAdmin}, run: update_config},
+		{Name: "read_config", Description: "Read the chaincode config", Roles: []string{RoleAnyone}, ReadOnly: true, run: read_config},
+		{Name: "describe", Description: "Read this catalog, or the entry of one function", Args: []FieldSpec{
+			{Name: "function", Type: "string", Description: "Name of a single function to describe"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: describe},
+		{Name: "migrate_state", Description: "Upgrade stored records to the current schema versions, one batch per call", Args: []FieldSpec{
+			{Name: "batch_size", Type: "string", Required: true, Description: "Records to upgrade in this call"},
+			{Name: "start_key", Type: "string", Description: "Restart the pass from this key"},
+		}, Roles: []string{RoleAdmin}, run: migrate_state},
+
+		// ---- generic ledger access ---- //
+		{Name: "read", Description: "Read a key from the ledger, personal data excluded", Args: []FieldSpec{
+			{Name: "key", Type: "string", Required: true, Description: "Key of the var to read"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read},
+
+		// ---- universities ---- //
+		{Name: "init_university", Description: "Register a university, by proposal when governance is enabled", Payload: &university_schema, Roles: []string{RoleAccreditor}, run: init_university},
+		{Name: "set_dean", Description: "End the current dean term and start one for the new dean", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "old_dean", Type: "string", Required: true, Description: "Name of the dean in office"},
+			{Name: "new_dean", Type: "string", Required: true, Description: "Name of the new dean"},
+			{Name: "new_dean_identity", Type: "string", Description: "Identity the new dean signs with, \"<msp>:<common name>\""},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: []string{RoleDean, RoleAccreditorOfRecord, RoleRegulator}, run: set_dean},
+		{Name: "set_dean_identity", Description: "Register the identity the dean of the current term signs with, once per term", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "identity", Type: "string", Required: true, MaxLength: 256, Description: "Identity the dean signs with, \"<msp>:<common name>\""},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: set_dean_identity},
+		{Name: "set_university_municipality", Description: "Set the municipality of the seat of the university, named on its diplomas", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "municipality", Type: "string", Required: true, MaxLength: 128, Description: "Municipality of the seat of the university"},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: []string{RoleDean, RoleAccreditorOfRecord, RoleRegulator}, run: set_university_municipality},
+		{Name: "read_university_by_cnpj", Description: "Read the university registered with a CNPJ", Args: []FieldSpec{
+			{Name: "cnpj", Type: "string", Required: true, Description: "CNPJ, with or without punctuation"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_university_by_cnpj},
+		{Name: "read_everything", Description: "Read all certificates of a university", Args: []FieldSpec{university_id_arg}, Scope: university_scope("args.university_id", arg_at(0)), Roles: university_readers, ReadOnly: true, run: read_all_certificates_from_university},
+		{Name: "getUniversityHistory", Description: "Read the history of a university", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: getHistory},
+		{Name: "read_dean_terms", Description: "Read all dean terms of a university", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_dean_terms},
+		{Name: "register_university_key", Description: "Set the P-256 public key the university signs exported credentials with off-chain", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "public_key", Type: "string", Required: true, Description: "PEM public key"},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: []string{RoleDean, RoleAccreditorOfRecord, RoleRegulator}, run: register_university_key},
+
+		// ---- units ---- //
+		{Name: "init_unit", Description: "Create a campus, faculty or department", Payload: &unit_schema, Scope: university_scope("payload.university_id", payload_field(unit_schema, "university_id")), Roles: []string{RoleDean}, run: init_unit},
+		{Name: "set_unit_officers", Description: "Replace the officers of a unit", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "unit_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the unit"},
+			{Name: "officers", Type: "string", Required: true, Description: "JSON array of officers, [{role, name, identity}]"},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: []string{RoleDean}, run: set_unit_officers},
+		{Name: "read_units", Description: "Read all units of a university", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_units},
+		{Name: "read_unit_certificates", Description: "Read all certificates issued by a unit", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "unit_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the unit"},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: university_readers, ReadOnly: true, run: read_unit_certificates},
+
+		// ---- programs ---- //
+		{Name: "init_program", Description: "Register a degree program, non-degree programs by the dean", Payload: &program_schema, Scope: university_scope("payload.university_id", payload_field(program_schema, "university_id")), Roles: []string{RoleAccreditorOfRecord, RoleRegulator, RoleDean}, run: init_program},
+		{Name: "recognize_program", Description: "Renew the recognition of a program", Payload: &recognition_schema, Scope: university_scope("payload.university_id", payload_field(recognition_schema, "university_id")), Roles: supervisors, run: recognize_program},
+		{Name: "read_programs", Description: "Read all programs of a university", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_programs},
+
+		// ---- delegations ---- //
+		{Name: "grant_delegation", Description: "Let a registrar issue on behalf of the dean", Payload: &delegation_schema, Scope: university_scope("payload.university_id", payload_field(delegation_schema, "university_id")), Roles: []string{RoleDean}, run: grant_delegation},
+		{Name: "revoke_delegation", Description: "Withdraw a registrar delegation", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "delegation_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the delegation"},
+			reason_arg,
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: []string{RoleDean}, run: revoke_delegation},
+		{Name: "read_active_delegations", Description: "Read the delegations of a university usable right now", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_active_delegations},
+
+		// ---- certificates ---- //
+		{Name: "init_cert", Description: "Issue a certificate", Payload: &certificate_schema, Scope: university_scope("payload.university_id", payload_field(certificate_schema, "university_id")), Roles: issuers, run: init_cert},
+		{Name: "issue_second_copy", Description: "Issue a second copy of a certificate by the university answering for it", Args: []FieldSpec{
+			certificate_id_arg,
+			{Name: "copy_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the copy"},
+			{Name: "responsible_university_doc", Type: "string", Required: true, Description: "CNPJ of the university answering for the certificate"},
+			{Name: "delegation_id", Type: "string", MaxLength: 64, Description: "Delegation used when a registrar submits"},
+		}, Scope: responsible_scope("args.certificate_id", arg_at(0)), Roles: issuers, run: issue_second_copy},
+		{Name: "verify_certificate", Description: "Check a certificate and who answers for it", Args: []FieldSpec{certificate_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: verify_certificate},
+
+		// ---- graduates ---- //
+		{Name: "read_graduate_portfolio", Description: "Read a graduate and all their certificates", Args: []FieldSpec{graduate_id_arg}, Transient: graduate_reader_transient, Scope: graduate_scope("args.graduate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: read_graduate_portfolio},
+		{Name: "link_certificate_graduate", Description: "Link a certificate to its graduate", Args: []FieldSpec{certificate_id_arg}, Transient: []FieldSpec{graduate_key_transient}, Roles: []string{RoleAdmin}, run: link_certificate_graduate},
+
+		// ---- transcripts and credit transfer ---- //
+		{Name: "issue_transcript", Description: "Attach a transcript to a certificate", Payload: &transcript_schema, Scope: certificate_scope("payload.certificate_id", payload_field(transcript_schema, "certificate_id")), Roles: issuers, run: issue_transcript},
+		{Name: "read_transcript", Description: "Read the transcript of a certificate", Args: []FieldSpec{certificate_id_arg}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: read_transcript},
+		{Name: "grant_credit_consent", Description: "Let a university recognize credits of a certificate's transcript, once", Args: []FieldSpec{
+			certificate_id_arg,
+			{Name: "receiving_university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university recognizing the credits"},
+		}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: []string{RoleGraduate}, run: grant_credit_consent},
+		{Name: "request_credit_recognition", Description: "Recognize courses of another university's transcript, with a consent granted by the graduate", Payload: &credit_recognition_schema, Scope: university_scope("payload.receiving_university_id", payload_field(credit_recognition_schema, "receiving_university_id")), Roles: issuers, run: request_credit_recognition},
+		{Name: "decide_credit_recognition", Description: "Confirm or reject a credit recognition", Args: []FieldSpec{
+			{Name: "recognition_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the recognition"},
+			{Name: "decision", Type: "string", Required: true, Description: "\"confirmed\" or \"rejected\""},
+			reason_arg,
+		}, Scope: recognition_scope("args.recognition_id", arg_at(0)), Roles: issuers, run: decide_credit_recognition},
+		{Name: "read_recognitions_by_graduate", Description: "Read all credit recognitions of a graduate", Args: []FieldSpec{graduate_id_arg}, Transient: graduate_reader_transient, Scope: graduate_scope("args.graduate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: read_recognitions_by_graduate},
+		{Name: "read_recognitions_by_university", Description: "Read all credit recognitions of a university", Args: []FieldSpec{university_id_arg}, Scope: university_scope("args.university_id", arg_at(0)), Roles: university_readers, ReadOnly: true, run: read_recognitions_by_university},
+
+		// ---- exports and imports ---- //
+		{Name: "export_vc", Description: "Export a certificate as a W3C Verifiable Credential", Args: []FieldSpec{certificate_id_arg}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: export_vc},
+		{Name: "export_openbadge", Description: "Export a non-degree certificate as an Open Badges 3.0 credential", Args: []FieldSpec{certificate_id_arg}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: export_openbadge},
+		{Name: "check_openbadge", Description: "Validate an Open Badges credential against the ledger", Args: []FieldSpec{
+			{Name: "badge", Type: "string", Required: true, Description: "The badge credential JSON"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: check_openbadge},
+		{Name: "export_diploma_xml", Description: "Export a certificate as unsigned diploma XML in the AionCerts layout, not a MEC Diploma Digital", Args: []FieldSpec{certificate_id_arg}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: export_diploma_xml},
+		{Name: "check_diploma_xml", Description: "Compare a diploma XML with the certificate it names", Args: []FieldSpec{
+			{Name: "diploma", Type: "string", Required: true, Description: "The diploma XML"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: check_diploma_xml},
+		{Name: "read_diploma_payload", Description: "Turn a diploma XML into an init_cert payload", Args: []FieldSpec{
+			{Name: "diploma", Type: "string", Required: true, Description: "The diploma XML"},
+			university_id_arg,
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_diploma_payload},
+		{Name: "export_europass", Description: "Export a certificate as a European Digital Credential", Args: []FieldSpec{certificate_id_arg}, Transient: graduate_reader_transient, Scope: certificate_scope("args.certificate_id", arg_at(0)), Roles: graduate_readers, ReadOnly: true, run: export_europass},
+
+		// ---- accreditation ---- //
+		{Name: "init_accreditor", Description: "Register an accrediting body", Payload: &accreditor_schema, Roles: []string{RoleAdmin}, run: init_accreditor},
+		{Name: "accredit_university", Description: "Grant or renew the accreditation of a university", Payload: &accreditation_schema, Roles: []string{RoleAccreditor}, run: accredit_university},
+		{Name: "set_accreditation_status", Description: "Suspend, revoke or reinstate an accreditation", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "status", Type: "string", Required: true, Description: "\"active\", \"suspended\" or \"revoked\""},
+			reason_arg,
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: set_accreditation_status},
+		{Name: "read_accreditation", Description: "Read the accreditation of a university and whether it is valid now", Args: []FieldSpec{university_id_arg}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_accreditation},
+
+		// ---- university lifecycle ---- //
+		{Name: "suspend_university", Description: "Block issuance of a university", Args: []FieldSpec{university_id_arg, reason_arg}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: suspend_university},
+		{Name: "reactivate_university", Description: "Lift the suspension of a university", Args: []FieldSpec{university_id_arg, reason_arg}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: reactivate_university},
+		{Name: "close_university", Description: "Close a university for good, its certificates stay verifiable", Args: []FieldSpec{university_id_arg, reason_arg}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: close_university},
+		{Name: "assign_custodian", Description: "Assign who answers for a closed university's records", Args: []FieldSpec{
+			university_id_arg,
+			{Name: "custodian_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the custodian university"},
+		}, Scope: university_scope("args.university_id", arg_at(0)), Roles: supervisors, run: assign_custodian},
+		{Name: "merge_universities", Description: "Merge a university into its successor", Args: []FieldSpec{
+			{Name: "absorbed_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the absorbed university"},
+			{Name: "successor_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the successor university"},
+			reason_arg,
+		}, Scope: university_scope("args.absorbed_id", arg_at(0)), Roles: supervisors, run: merge_universities},
+
+		// ---- governance ---- //
+		{Name: "propose", Description: "Open a governance proposal", Payload: &proposal_schema, Roles: []string{RoleMember}, run: propose},
+		{Name: "vote", Description: "Vote on a governance proposal, the approving vote executes it", Args: []FieldSpec{
+			{Name: "proposal_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the proposal"},
+			{Name: "vote", Type: "string", Required: true, Description: "\"yes\" or \"no\""},
+		}, Roles: []string{RoleMember}, run: vote},
+		{Name: "close_proposal", Description: "Expire a proposal past its deadline", Args: []FieldSpec{
+			{Name: "proposal_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the proposal"},
+		}, Roles: []string{RoleAnyone}, run: close_proposal},
+		{Name: "read_proposals", Description: "List governance proposals", Args: []FieldSpec{
+			{Name: "status", Type: "string", Description: "Only proposals with this status"},
+		}, Roles: []string{RoleAnyone}, ReadOnly: true, run: read_proposals},
+	}
+}
+
+// ========================================================
+// Find Handler - the registered handler of a function name
+// ========================================================
+func find_handler(name string) (Handler, bool) {
+	for _, handler := range handlers {
+		if handler.Name == name {
+			return handler, true
+		}
+	}
+	return Handler{}, false
+}
+
+// ========================================================
+// Call Handler - check the arguments and the roles of the caller, then run the handler
+// ========================================================
+func call_handler(stub shim.ChaincodeStubInterface, handler Handler, args []string) pb.Response {
+	err := check_args(handler, args)
+	if err != nil {
+		return error_response(err)
+	}
+	err = authorize(stub, handler, args)
+	if err != nil {
+		return error_response(err)
+	}
+	return handler.run(stub, args)
+}
+
+// ========================================================
+// Check Args - the arguments match the spec of the handler
+//
+// Payload handlers take one JSON document, or their legacy positional arguments, parse_payload checks the fields.
+// Positional arguments are counted, required ones must not be empty and none may exceed its max length.
+// ========================================================
+func check_args(handler Handler, args []string) error {
+	if handler.Payload != nil {
+		if len(args) == 1 || accepts_positional(*handler.Payload, len(args)) {
+			return nil
+		}
+		return new_error(CodeInvalidArgument, "Incorrect number of arguments. "+handler.Name+" expects 1 JSON payload")
+	}
+
+	required := 0
+	for _, spec := range handler.Args {
+		if spec.Required {
+			required++
+		}
+	}
+	if len(args) < required || len(args) > len(handler.Args) {
+		expected := strconv.Itoa(len(handler.Args))
+		if required < len(handler.Args) {
+			expected = strconv.Itoa(required) + " to " + expected
+		}
+		return new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting "+expected)
+	}
+
+	var field_errors []FieldError
+	for i, value := range args {
+		spec := handler.Args[i]
+		if spec.Required && len(value) == 0 {
+			field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "is required"})
+		}
+		if spec.MaxLength > 0 && len(value) > spec.MaxLength {
+			field_errors = append(field_errors, FieldError{Field: spec.Name, Message: "must be at most " + strconv.Itoa(spec.MaxLength) + " characters"})
+		}
+	}
+	if len(field_errors) > 0 {
+		return ValidationError{Schema: handler.Name, Fields: field_errors}
+	}
+	return nil
+}
+
+// ========================================================
+// Authorize - the caller holds one of the roles of the handler, on the target its scope resolves
+// ========================================================
+func authorize(stub shim.ChaincodeStubInterface, handler Handler, args []string) error {
+	var target Target
+	var err error
+	if contains(handler.Roles, RoleAnyone) {
+		return nil
+	}
+	if handler.Scope != nil {
+		target, err = handler.Scope.resolve(stub, args)
+		if err != nil {
+			return err
+		}
+	}
+	for _, role := range handler.Roles {
+		if check, found := role_checks[role]; found && check(stub, target) {
+			return nil
+		}
+	}
+	identity, err := get_creator(stub)
+	if err != nil {
+		return err
+	}
+	return new_error(CodeUnauthorized, "Identity '"+identity.Id+"' holds none of the roles "+handler.Name+" requires - "+strings.Join(handler.Roles, ", "))
+}
+
+// role checks, each true when the caller holds the role on the target
+var role_checks = map[string]func(shim.ChaincodeStubInterface, Target) bool{
+	RoleAnyone: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		return true
+	},
+	RoleAdmin: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		return require_admin(stub) == nil
+	},
+	RoleRegulator: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		accreditor, err := get_creator_accreditor(stub)
+		if err != nil {
+			return false
+		}
+		config, err := get_config(stub)
+		return err == nil && len(config.RegulatorMsp) > 0 && accreditor.MspId == config.RegulatorMsp
+	},
+	RoleAccreditor: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		_, err := get_creator_accreditor(stub)
+		return err == nil
+	},
+	RoleAccreditorOfRecord: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		accreditor, err := get_creator_accreditor(stub)
+		if err != nil {
+			return false
+		}
+		for _, university_id := range target.Universities {
+			accreditation, err := get_accreditation(stub, university_id)
+			if err == nil && accreditation.AccreditorId == accreditor.Id {
+				return true
+			}
+		}
+		return false
+	},
+	RoleDean: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		for _, university_id := range target.Universities {
+			university, err := get_university(stub, university_id)
+			if err != nil {
+				continue
+			}
+			if _, err = require_signer(stub, university); err == nil {
+				return true
+			}
+		}
+		return false
+	},
+	RoleRegistrar: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		for _, university_id := range target.Universities {
+			university, err := get_university(stub, university_id)
+			if err == nil && require_staff(stub, university) == nil {
+				return true
+			}
+		}
+		return false
+	},
+	RoleMember: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		config, err := get_config(stub)
+		if err != nil {
+			return false
+		}
+		_, err = require_member(stub, config.Governance.Members)
+		return err == nil
+	},
+	RoleGraduate: func(stub shim.ChaincodeStubInterface, target Target) bool {
+		return len(target.Certificates) > 0 && proves_graduate(stub, target.Certificates) == nil
+	},
+}
+
+// ----- Arg Value ----- //
+// reads one value out of the arguments of a call
+type arg_value func(args []string) string
+
+// positional argument i
+func arg_at(i int) arg_value {
+	return func(args []string) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+}
+
+// field of the JSON payload, or its legacy positional argument
+func payload_field(schema PayloadSchema, name string) arg_value {
+	return func(args []string) string {
+		if len(args) == 1 && strings.HasPrefix(strings.TrimSpace(args[0]), "{") {
+			fields, err := decode_json_object(args[0])
+			if err != nil {
+				return ""
+			}
+			value, _ := fields[name].(string)
+			return value
+		}
+		for i, positional := range schema.Positional {
+			if positional == name && i < len(args) {
+				return args[i]
+			}
+		}
+		return ""
+	}
+}
+
+// ========================================================
+// University Scope - the call acts on the university whose id the value holds
+// ========================================================
+func university_scope(from string, value arg_value) *Scope {
+	return &Scope{From: from, resolve: func(stub shim.ChaincodeStubInterface, args []string) (Target, error) {
+		return Target{Universities: []string{value(args)}}, nil
+	}}
+}
+
+// ========================================================
+// Certificate Scope - the call acts on the issuer and the graduate of a certificate
+// ========================================================
+func certificate_scope(from string, value arg_value) *Scope {
+	return &Scope{From: from + " (issuer and graduate of the certificate)", resolve: func(stub shim.ChaincodeStubInterface, args []string) (Target, error) {
+		certificate, err := get_certificate(stub, value(args))
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Universities: []string{certificate.IssuedBy}, Certificates: []string{certificate.Id}}, nil
+	}}
+}
+
+// ========================================================
+// Responsible Scope - the call acts on the university answering for a certificate today, see second copies
+// ========================================================
+func responsible_scope(from string, value arg_value) *Scope {
+	return &Scope{From: from + " (university answering for the certificate)", resolve: func(stub shim.ChaincodeStubInterface, args []string) (Target, error) {
+		certificate, err := get_certificate(stub, value(args))
+		if err != nil {
+			return Target{}, err
+		}
+		issuer, err := get_university(stub, certificate.University.Id)
+		if err != nil {
+			return Target{}, err
+		}
+		responsible, err := get_responsible_university(stub, issuer)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Universities: []string{responsible.Id}}, nil
+	}}
+}
+
+// ========================================================
+// Graduate Scope - the call acts on a graduate and every university that issued them a certificate
+// ========================================================
+func graduate_scope(from string, value arg_value) *Scope {
+	return &Scope{From: from + " (graduate and the issuers of their certificates)", resolve: func(stub shim.ChaincodeStubInterface, args []string) (Target, error) {
+		graduate, err := get_graduate(stub, value(args))
+		if err != nil {
+			return Target{}, err
+		}
+		target := Target{Certificates: graduate.Certificates}
+		for _, certificate_id := range graduate.Certificates {
+			certificate, err := get_certificate(stub, certificate_id)
+			if err != nil {
+				return Target{}, err
+			}
+			if !contains(target.Universities, certificate.IssuedBy) {
+				target.Universities = append(target.Universities, certificate.IssuedBy)
+			}
+		}
+		return target, nil
+	}}
+}
+
+// ========================================================
+// Recognition Scope - the call acts on the university answering for the source of a credit recognition
+// ========================================================
+func recognition_scope(from string, value arg_value) *Scope {
+	return &Scope{From: from + " (university answering for the source transcript)", resolve: func(stub shim.ChaincodeStubInterface, args []string) (Target, error) {
+		recognition, err := get_credit_recognition(stub, value(args))
+		if err != nil {
+			return Target{}, err
+		}
+		source, err := get_university(stub, recognition.SourceUniversityId)
+		if err != nil {
+			return Target{}, err
+		}
+		responsible, err := get_responsible_university(stub, source)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Universities: []string{responsible.Id}}, nil
+	}}
+}
+
+// ============================================================================================================================
+// Describe - the catalog of every function, its arguments, roles and whether it is read-only
+//
+// Inputs - Array of strings
+//
+//	0
+//	function (optional)
+//	"init_cert"
+//
+// Returns - Catalog JSON, or the Handler JSON of the named function
+// ============================================================================================================================
+func describe(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("starting describe")
+
+	if len(args) > 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 0 or 1"))
+	}
+
+	if len(args) == 1 {
+		handler, found := find_handler(args[0])
+		if !found {
+			return error_response(new_error(CodeNotFound, "Function does not exist - "+args[0]))
+		}
+		handlerAsBytes, _ := json.Marshal(handler) //convert to array of bytes
+		return shim.Success(handlerAsBytes)
+	}
+
+	catalog := Catalog{Functions: handlers, Roles: role_descriptions, ErrorCodes: error_codes}
+	catalogAsBytes, _ := json.Marshal(catalog) //convert to array of bytes
+	return shim.Success(catalogAsBytes)
+}
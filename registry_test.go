@@ -0,0 +1,74 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDispatcherChecks(t *testing.T) {
+	stub := new_test_ledger()
+
+	tests := []struct {
+		name     string
+		mspId    string
+		cn       string
+		function string
+		args     []string
+		code     string
+	}{
+		{"write is gone", "Org1MSP", "admin", "write", []string{"k", "v"}, CodeInvalidArgument},
+		{"too few arguments", "Org1MSP", "admin", "read", []string{}, CodeInvalidArgument},
+		{"too many arguments", "Org1MSP", "admin", "read", []string{"a", "b"}, CodeInvalidArgument},
+		{"empty required argument", "Org1MSP", "admin", "read", []string{""}, CodeInvalidArgument},
+		{"argument over max length", "Org1MSP", "admin", "read_dean_terms", []string{strings.Repeat("u", 65)}, CodeInvalidArgument},
+		{"payload with wrong count", "Org1MSP", "admin", "init_accreditor", []string{"{}", "{}"}, CodeInvalidArgument},
+		{"admin only by a stranger", "Org2MSP", "mallory", "init_accreditor", []string{`{"id": "a1", "name": "MEC", "mspId": "MECMSP"}`}, CodeUnauthorized},
+		{"accreditor only by a stranger", "Org2MSP", "mallory", "init_university", []string{`{"id": "u1"}`}, CodeUnauthorized},
+		{"dean only by a stranger", "Org2MSP", "mallory", "register_university_key", []string{"u1", "key"}, CodeUnauthorized},
+		{"anyone", "Org2MSP", "mallory", "read_config", []string{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := stub.as(test.mspId, test.cn).invoke(test.function, test.args...)
+			if test.code == "" {
+				ok(t, res)
+			} else {
+				fails(t, res, test.code)
+			}
+		})
+	}
+}
+
+func TestDescribeShowsScope(t *testing.T) {
+	stub := new_test_ledger()
+	var handler Handler
+
+	payload := ok(t, stub.invoke("describe", "read_transcript"))
+	if err := json.Unmarshal(payload, &handler); err != nil {
+		t.Fatal(err)
+	}
+	if handler.Scope == nil || !strings.HasPrefix(handler.Scope.From, "args.certificate_id") {
+		t.Fatalf("expected the certificate scope, got %+v", handler.Scope)
+	}
+	fails(t, stub.invoke("describe", "write"), CodeNotFound)
+}
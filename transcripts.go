@@ -0,0 +1,285 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Transcript Payload ----- //
+type TranscriptPayload struct {
+	CertificateId string   `json:"certificate_id"`
+	Courses       []Course `json:"courses"`
+	DelegationId  string   `json:"delegation_id"`
+}
+
+var transcript_schema = PayloadSchema{
+	Name: "transcript",
+	Fields: []FieldSpec{
+		{Name: "certificate_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the certificate the transcript belongs to"},
+		{Name: "courses", Type: "array", Required: true, Description: "Courses taken, [{code, name, term, grade, creditHours, status}]"},
+		{Name: "delegation_id", Type: "string", MaxLength: 64, Description: "Delegation used when a registrar submits instead of the dean"},
+	},
+}
+
+// grades go from 0 to 10, only passed and failed courses count towards the GPA
+const max_grade = 10
+
+var course_statuses = map[string]bool{"passed": true, "failed": true, "exempt": true}
+
+// ========================================================
+// Certificate Digest - sha256 of the fields that identify a certificate, hex encoded
+//
+// Only fields that never change after issuance are covered, so graduate linking keeps the digest. The document and
+// date are digested in canonical form, so migrations that canonicalize them keep it too and never touch transcripts.
+// ========================================================
+func certificate_digest(certificate Certificate) string {
+	canonicalize_certificate_cpf(&certificate)
+	certificate.Date, _ = canonical_date(certificate.Date) //dates that never parsed are digested as issued
+	digested := []string{
+		certificate.Id,
+		certificate.Name,
+		certificate.Document,
+		certificate.Body,
+		certificate.City,
+		certificate.Date,
+		certificate.University.Id,
+		certificate.IssuedBy,
+		certificate.ProgramId,
+	}
+	digestedAsBytes, _ := json.Marshal(digested) //a JSON array keeps field boundaries unambiguous
+	digest := sha256.Sum256(digestedAsBytes)
+	return hex.EncodeToString(digest[:])
+}
+
+// ========================================================
+// Get Transcript - get the transcript of a certificate from ledger
+// ========================================================
+func get_transcript(stub shim.ChaincodeStubInterface, certificate_id string) (Transcript, error) {
+	var transcript Transcript
+	transcriptKey, _ := stub.CreateCompositeKey("transcript", []string{certificate_id})
+	transcriptAsBytes, err := stub.GetState(transcriptKey)
+	if err != nil {
+		return transcript, errors.New("Failed to get transcript - " + certificate_id)
+	}
+	if transcriptAsBytes == nil {
+		return transcript, new_error(CodeNotFound, "Certificate '"+certificate_id+"' has no transcript")
+	}
+	err = json.Unmarshal(transcriptAsBytes, &transcript) //un stringify it aka JSON.parse()
+	if err != nil {
+		return transcript, errors.New("Transcript is not readable - " + certificate_id)
+	}
+	return transcript, nil
+}
+
+// ========================================================
+// Put Transcript - store a transcript
+// ========================================================
+func put_transcript(stub shim.ChaincodeStubInterface, transcript Transcript) error {
+	transcriptKey, _ := stub.CreateCompositeKey("transcript", []string{transcript.CertificateId})
+	return put_asset(stub, transcriptKey, &transcript)
+}
+
+// ========================================================
+// Check Courses - every course needs a code, name and term, a known status and sane grade and credit hours
+// ========================================================
+func check_courses(courses []Course) []FieldError {
+	var field_errors []FieldError
+	if len(courses) == 0 {
+		field_errors = append(field_errors, FieldError{Field: "courses", Message: "must list at least one course"})
+	}
+	for i, course := range courses {
+		field := "courses[" + strconv.Itoa(i) + "]"
+		if len(course.Code) == 0 || len(course.Name) == 0 || len(course.Term) == 0 {
+			field_errors = append(field_errors, FieldError{Field: field, Message: "needs a code, a name and a term"})
+		}
+		if !course_statuses[course.Status] {
+			field_errors = append(field_errors, FieldError{Field: field + ".status", Message: "must be passed, failed or exempt"})
+		}
+		if course.Grade < 0 || course.Grade > max_grade {
+			field_errors = append(field_errors, FieldError{Field: field + ".grade", Message: "must be between 0 and " + strconv.Itoa(max_grade)})
+		}
+		if course.CreditHours <= 0 {
+			field_errors = append(field_errors, FieldError{Field: field + ".creditHours", Message: "must be positive"})
+		}
+	}
+	return field_errors
+}
+
+// ========================================================
+// Compute Totals - credit hours earned and the credit weighted GPA, rounded to two decimals
+// ========================================================
+func compute_totals(courses []Course) (int, float64) {
+	var earned, graded int
+	var points float64
+	for _, course := range courses {
+		if course.Status != "failed" {
+			earned += course.CreditHours
+		}
+		if course.Status != "exempt" {
+			graded += course.CreditHours
+			points += course.Grade * float64(course.CreditHours)
+		}
+	}
+	if graded == 0 {
+		return earned, 0
+	}
+	return earned, math.Floor(points/float64(graded)*100+0.5) / 100
+}
+
+// ============================================================================================================================
+// Issue Transcript - attach the transcript to a certificate, by the university that issued the certificate
+//
+// Must be submitted by the dean in office, or by a registrar naming a delegation from them in delegation_id.
+// The transcript records the digest of the certificate, totals and GPA are computed here.
+//
+// Inputs - JSON payload validated against transcript_schema
+//
+//	{
+//		"certificate_id": "c123",
+//		"courses": [{"code": "MAT101", "name": "Cálculo I", "term": "2013/1", "grade": 8.5, "creditHours": 90, "status": "passed"}],
+//		"delegation_id": "d123"
+//	}
+//
+// ============================================================================================================================
+func issue_transcript(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting issue_transcript")
+
+	//input sanitation
+	var payload TranscriptPayload
+	err = parse_payload(args, transcript_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	field_errors := check_courses(payload.Courses)
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: transcript_schema.Name, Fields: field_errors})
+	}
+
+	certificate, err := get_certificate(stub, payload.CertificateId)
+	if err != nil {
+		return error_response(err)
+	}
+	if len(certificate.CopyOf) > 0 {
+		return error_response(new_error(CodeInvalidArgument, "Certificate '"+certificate.Id+"' is a second copy, attach the transcript to '"+certificate.CopyOf+"'"))
+	}
+
+	//only the issuing university, while it may still issue
+	university, err := get_university(stub, certificate.IssuedBy)
+	if err != nil {
+		return error_response(err)
+	}
+	if university.Status != "active" {
+		return error_response(new_error(CodeConflict, "University '"+university.Id+"' is "+university.Status+" and cannot issue transcripts"))
+	}
+	var units []string
+	if len(certificate.UnitId) > 0 {
+		units, err = get_unit_chain(stub, university.Id, certificate.UnitId)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+	signer, err := authorize_issuance(stub, university, payload.DelegationId, certificate.ProgramId, units)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//a transcript is issued once
+	_, err = get_transcript(stub, certificate.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "Certificate '"+certificate.Id+"' already has a transcript"))
+	}
+
+	var transcript Transcript
+	transcript.ObjectType = "transcript"
+	transcript.SchemaVersion = TranscriptSchemaVersion
+	transcript.CertificateId = certificate.Id
+	transcript.CertificateDigest = certificate_digest(certificate)
+	transcript.UniversityId = university.Id
+	transcript.GraduateId = certificate.GraduateId
+	transcript.Courses = payload.Courses
+	transcript.TotalCreditHours, transcript.Gpa = compute_totals(payload.Courses)
+	transcript.DeanTerm = signer.Term.Id
+	transcript.SignedBy = signer.Identity
+	transcript.Delegation = signer.Delegation
+
+	err = put_transcript(stub, transcript)
+	if err != nil {
+		fmt.Println("Could not store transcript")
+		return error_response(err)
+	}
+
+	fmt.Println("- end issue_transcript")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Transcript - get the transcript of a certificate, with the same access rules as the graduate portfolio
+//
+// Transient - "cpf" or "passport" when the caller proves the document instead of acting for an institution
+//
+// Inputs - Array of strings
+//
+//	0
+//	certificate_id
+//	"c123"
+//
+// Returns - { "transcript": Transcript, "linked": true when the certificate still matches the recorded digest }
+// ============================================================================================================================
+func read_transcript(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Result struct {
+		Transcript Transcript `json:"transcript"`
+		Linked     bool       `json:"linked"`
+	}
+	var result Result
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	transcript, err := get_transcript(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	certificate, err := get_certificate(stub, transcript.CertificateId)
+	if err != nil {
+		return error_response(err)
+	}
+
+	err = require_graduate_reader(stub, Graduate{Id: transcript.GraduateId}, []Certificate{certificate})
+	if err != nil {
+		return error_response(err)
+	}
+
+	result.Transcript = transcript
+	result.Linked = certificate_digest(certificate) == transcript.CertificateDigest
+	resultAsBytes, _ := json.Marshal(result) //convert to array of bytes
+	return shim.Success(resultAsBytes)
+}
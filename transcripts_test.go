@@ -0,0 +1,138 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestComputeTotals(t *testing.T) {
+	tests := []struct {
+		name    string
+		courses []Course
+		earned  int
+		gpa     float64
+	}{
+		{"one course", []Course{{Grade: 8, CreditHours: 60, Status: "passed"}}, 60, 8},
+		{"weighted by credit hours", []Course{{Grade: 10, CreditHours: 90, Status: "passed"}, {Grade: 5, CreditHours: 30, Status: "passed"}}, 120, 8.75},
+		{"failed courses count for the GPA only", []Course{{Grade: 9, CreditHours: 60, Status: "passed"}, {Grade: 3, CreditHours: 60, Status: "failed"}}, 60, 6},
+		{"exempt courses count for the hours only", []Course{{Grade: 7, CreditHours: 60, Status: "passed"}, {Grade: 0, CreditHours: 60, Status: "exempt"}}, 120, 7},
+		{"only exempt courses", []Course{{CreditHours: 60, Status: "exempt"}}, 60, 0},
+		{"rounded to two decimals", []Course{{Grade: 7, CreditHours: 30, Status: "passed"}, {Grade: 8, CreditHours: 30, Status: "passed"}, {Grade: 8, CreditHours: 30, Status: "passed"}}, 90, 7.67},
+	}
+	for _, test := range tests {
+		earned, gpa := compute_totals(test.courses)
+		if earned != test.earned || gpa != test.gpa {
+			t.Errorf("%s - %d hours and GPA %v, expected %d and %v", test.name, earned, gpa, test.earned, test.gpa)
+		}
+	}
+}
+
+const test_courses = `[{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 8, "creditHours": 60, "status": "passed"}]`
+
+func TestIssueTranscript(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_second_copy", "c1", "c2", test_cnpj))
+
+	tests := []struct {
+		name    string
+		caller  string
+		payload string
+		code    string
+	}{
+		{"no courses", "Org1MSP:joao", `{"certificate_id": "c1", "courses": []}`, CodeInvalidArgument},
+		{"unknown status", "Org1MSP:joao", `{"certificate_id": "c1", "courses": [{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 8, "creditHours": 60, "status": "dropped"}]}`, CodeInvalidArgument},
+		{"grade over the maximum", "Org1MSP:joao", `{"certificate_id": "c1", "courses": [{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 11, "creditHours": 60, "status": "passed"}]}`, CodeInvalidArgument},
+		{"no credit hours", "Org1MSP:joao", `{"certificate_id": "c1", "courses": [{"code": "MAT101", "name": "Calculus", "term": "2013/1", "grade": 8, "creditHours": 0, "status": "passed"}]}`, CodeInvalidArgument},
+		{"by a stranger", "Org9MSP:mallory", `{"certificate_id": "c1", "courses": ` + test_courses + `}`, CodeUnauthorized},
+		{"of a second copy", "Org1MSP:joao", `{"certificate_id": "c2", "courses": ` + test_courses + `}`, CodeInvalidArgument},
+		{"of an unknown certificate", "Org1MSP:joao", `{"certificate_id": "c9", "courses": ` + test_courses + `}`, CodeNotFound},
+		{"by the dean", "Org1MSP:joao", `{"certificate_id": "c1", "courses": ` + test_courses + `}`, ""},
+		{"twice", "Org1MSP:joao", `{"certificate_id": "c1", "courses": ` + test_courses + `}`, CodeAlreadyExists},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).invoke("issue_transcript", test.payload)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	transcript, err := get_transcript(stub, "c1")
+	if err != nil || transcript.TotalCreditHours != 60 || transcript.Gpa != 8 || transcript.SignedBy != "Org1MSP:joao" || transcript.UniversityId != "u1" {
+		t.Fatalf("transcript %+v %v", transcript, err)
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(nil))
+	ok(t, stub.as("Org1MSP", "joao").invoke("issue_transcript", `{"certificate_id": "c1", "courses": `+test_courses+`}`))
+
+	readers := []struct {
+		name      string
+		caller    string
+		transient map[string]string
+		code      string
+	}{
+		{"stranger", "Org9MSP:mallory", nil, CodeUnauthorized},
+		{"stranger with another document", "Org9MSP:mallory", map[string]string{"cpf": "111.444.777-35"}, CodeUnauthorized},
+		{"graduate proving the document", "Org9MSP:maria", map[string]string{"cpf": test_cpf}, ""},
+		{"dean of the issuer", "Org1MSP:joao", nil, ""},
+		{"accreditor of the issuer", "MECMSP:inspector", nil, ""},
+	}
+	for _, test := range readers {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).with(test.transient).invoke("read_transcript", "c1")
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			var result struct {
+				Linked bool `json:"linked"`
+			}
+			json.Unmarshal(ok(t, res), &result)
+			if !result.Linked {
+				t.Fatal("transcript is not linked to the certificate it was issued for")
+			}
+		})
+	}
+
+	//a certificate changed behind the chaincode no longer matches its transcript
+	certificateAsBytes, _ := stub.GetState("c1")
+	var fields map[string]interface{}
+	json.Unmarshal(certificateAsBytes, &fields)
+	fields["name"] = "Mario"
+	stub.put("c1", fields)
+	var result struct {
+		Linked bool `json:"linked"`
+	}
+	json.Unmarshal(ok(t, stub.as("Org1MSP", "joao").with(nil).invoke("read_transcript", "c1")), &result)
+	if result.Linked {
+		t.Fatal("tampered certificate still matches its transcript")
+	}
+}
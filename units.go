@@ -0,0 +1,373 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ----- Unit Payload ----- //
+type UnitPayload struct {
+	Id           string    `json:"id"`
+	UniversityId string    `json:"university_id"`
+	ParentId     string    `json:"parent_id"`
+	Kind         string    `json:"kind"`
+	Name         string    `json:"name"`
+	Address      string    `json:"address"`
+	City         string    `json:"city"`
+	Officers     []Officer `json:"officers"`
+}
+
+var unit_schema = PayloadSchema{
+	Name: "unit",
+	Fields: []FieldSpec{
+		{Name: "id", Type: "string", Required: true, MaxLength: 64, Description: "UUID of unit"},
+		{Name: "university_id", Type: "string", Required: true, MaxLength: 64, Description: "Id of the university"},
+		{Name: "parent_id", Type: "string", MaxLength: 64, Description: "Id of the unit this one belongs to"},
+		{Name: "kind", Type: "string", Required: true, MaxLength: 16, Description: "\"campus\", \"faculty\" or \"department\""},
+		{Name: "name", Type: "string", Required: true, MaxLength: 256, Description: "Name of unit"},
+		{Name: "address", Type: "string", MaxLength: 512, Description: "Street address"},
+		{Name: "city", Type: "string", Required: true, MaxLength: 128, Description: "City of the unit"},
+		{Name: "officers", Type: "array", Description: "Responsible officers, [{role, name, identity}]"},
+	},
+}
+
+// rank of each kind of unit, a unit may only belong to a unit of lower rank
+var unit_kinds = map[string]int{"campus": 0, "faculty": 1, "department": 2}
+
+// ========================================================
+// Get Unit - get a unit of a university from ledger
+// ========================================================
+func get_unit(stub shim.ChaincodeStubInterface, university_id string, unit_id string) (Unit, error) {
+	var unit Unit
+	unitKey, _ := stub.CreateCompositeKey("unit", []string{university_id, unit_id})
+	unitAsBytes, err := stub.GetState(unitKey)
+	if err != nil {
+		return unit, errors.New("Failed to get unit - " + unit_id)
+	}
+	if unitAsBytes == nil {
+		return unit, new_error(CodeNotFound, "Unit '"+unit_id+"' does not exist in university '"+university_id+"'")
+	}
+	err = json.Unmarshal(unitAsBytes, &unit) //un stringify it aka JSON.parse()
+	if err != nil {
+		return unit, errors.New("Unit is not readable - " + unit_id)
+	}
+	return unit, nil
+}
+
+// ========================================================
+// Put Unit - store a unit
+// ========================================================
+func put_unit(stub shim.ChaincodeStubInterface, unit Unit) error {
+	unitKey, _ := stub.CreateCompositeKey("unit", []string{unit.UniversityId, unit.Id})
+	return put_asset(stub, unitKey, &unit)
+}
+
+// ========================================================
+// Get Unit Chain - ids of the unit and all units above it, the unit first
+// ========================================================
+func get_unit_chain(stub shim.ChaincodeStubInterface, university_id string, unit_id string) ([]string, error) {
+	var chain []string
+	for len(unit_id) > 0 {
+		if len(chain) > len(unit_kinds) {
+			return chain, new_error(CodeInvalidArgument, "Unit '"+unit_id+"' has too many levels above it")
+		}
+		unit, err := get_unit(stub, university_id, unit_id)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, unit.Id)
+		unit_id = unit.ParentId
+	}
+	return chain, nil
+}
+
+// ========================================================
+// Index Certificate Unit - list a certificate under the unit that issued it
+// ========================================================
+func index_certificate_unit(stub shim.ChaincodeStubInterface, university_id string, unit_id string, certificate_id string) error {
+	indexKey, err := stub.CreateCompositeKey("cert~unit", []string{university_id, unit_id, certificate_id})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(indexKey, []byte{0x00}) //the key is the index, the value is irrelevant
+}
+
+// ========================================================
+// Check Officers - every officer needs a role and a name, identities are optional
+// ========================================================
+func check_officers(officers []Officer) []FieldError {
+	var field_errors []FieldError
+	for i, officer := range officers {
+		field := "officers[" + strconv.Itoa(i) + "]"
+		if len(officer.Role) == 0 || len(officer.Name) == 0 {
+			field_errors = append(field_errors, FieldError{Field: field, Message: "needs a role and a name"})
+		}
+		if len(officer.Identity) > 0 {
+			if err := check_identity_format(officer.Identity); err != nil {
+				field_errors = append(field_errors, FieldError{Field: field + ".identity", Message: error_message(err)})
+			}
+		}
+	}
+	return field_errors
+}
+
+// ============================================================================================================================
+// Init Unit - create a campus, faculty or department of a university, dean only
+//
+// Inputs - JSON payload validated against unit_schema
+//
+//	{
+//		"id": "fac-eng",
+//		"university_id": "u123",
+//		"parent_id": "campus-centro",
+//		"kind": "faculty",
+//		"name": "Faculdade de Engenharia",
+//		"address": "Av. Paulista, 1000",
+//		"city": "Sao Paulo",
+//		"officers": [{"role": "coordinator", "name": "Maria", "identity": "Org1MSP:maria@uniuni.edu.br"}]
+//	}
+//
+// ============================================================================================================================
+func init_unit(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting init_unit")
+
+	//input sanitation
+	var payload UnitPayload
+	err = parse_payload(args, unit_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+	field_errors := check_officers(payload.Officers)
+	rank, known := unit_kinds[payload.Kind]
+	if !known {
+		field_errors = append(field_errors, FieldError{Field: "kind", Message: "must be campus, faculty or department"})
+	}
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: unit_schema.Name, Fields: field_errors})
+	}
+
+	university, err := get_university(stub, payload.UniversityId)
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_signer(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check the parent is a higher level unit of the same university
+	if len(payload.ParentId) > 0 {
+		parent, err := get_unit(stub, university.Id, payload.ParentId)
+		if err != nil {
+			return error_response(err)
+		}
+		if unit_kinds[parent.Kind] >= rank {
+			return error_response(new_error(CodeInvalidArgument, "A "+payload.Kind+" cannot belong to a "+parent.Kind))
+		}
+	}
+
+	//check if unit already exists
+	_, err = get_unit(stub, university.Id, payload.Id)
+	if err == nil {
+		return error_response(new_error(CodeAlreadyExists, "This unit already exists - "+payload.Id))
+	}
+
+	var unit Unit
+	unit.ObjectType = "unit"
+	unit.SchemaVersion = UnitSchemaVersion
+	unit.Id = payload.Id
+	unit.UniversityId = university.Id
+	unit.ParentId = payload.ParentId
+	unit.Kind = payload.Kind
+	unit.Name = payload.Name
+	unit.Address = payload.Address
+	unit.City = payload.City
+	unit.Officers = payload.Officers
+
+	err = put_unit(stub, unit)
+	if err != nil {
+		fmt.Println("Could not store unit")
+		return error_response(err)
+	}
+
+	fmt.Println("- end init_unit")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Set Unit Officers - replace the responsible officers of a unit, dean only
+//
+// Inputs - Array of Strings
+//
+//	0             | 1         | 2
+//	university_id | unit_id   | officers (JSON array)
+//
+// "u123"         | "fac-eng" | "[{\"role\": \"coordinator\", \"name\": \"Maria\"}]"
+// ============================================================================================================================
+func set_unit_officers(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	var officers []Officer
+	fmt.Println("starting set_unit_officers")
+
+	if len(args) != 3 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+	err = json.Unmarshal([]byte(args[2]), &officers)
+	if err != nil {
+		return error_response(ValidationError{Schema: unit_schema.Name, Fields: []FieldError{{Field: "officers", Message: "must be a JSON array of officers"}}})
+	}
+	field_errors := check_officers(officers)
+	if len(field_errors) > 0 {
+		return error_response(ValidationError{Schema: unit_schema.Name, Fields: field_errors})
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_signer(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+
+	unit, err := get_unit(stub, university.Id, args[1])
+	if err != nil {
+		return error_response(err)
+	}
+	unit.Officers = officers
+	err = put_unit(stub, unit)
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end set_unit_officers")
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Read Units - list all units of a university
+//
+// Inputs - Array of strings
+//
+//	0
+//	university_id
+//	"u123"
+//
+// ============================================================================================================================
+func read_units(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var units []Unit
+
+	if len(args) != 1 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 1"))
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("unit", []string{args[0]})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		var unit Unit
+		json.Unmarshal(record.Value, &unit) //un stringify it aka JSON.parse()
+		units = append(units, unit)
+	}
+
+	unitsAsBytes, _ := json.Marshal(units) //convert to array of bytes
+	return shim.Success(unitsAsBytes)
+}
+
+// ============================================================================================================================
+// Read Unit Certificates - get all certificates issued by a unit, admins, accreditors and staff of the university only
+//
+// Inputs - Array of strings
+//
+//	0             | 1
+//	university_id | unit_id
+//	"u123"        | "fac-eng"
+//
+// Returns - { "certificates": [Certificate] }
+// ============================================================================================================================
+func read_unit_certificates(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	type Everything struct {
+		Certificates []Certificate `json:"certificates"`
+	}
+	var everything Everything
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	err = require_university_reader(stub, university)
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = get_unit(stub, args[0], args[1])
+	if err != nil {
+		return error_response(err)
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey("cert~unit", []string{args[0], args[1]})
+	if err != nil {
+		return error_response(err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return error_response(err)
+		}
+		_, keyParts, err := stub.SplitCompositeKey(record.Key)
+		if err != nil {
+			return error_response(err)
+		}
+		certificate, err := get_certificate(stub, keyParts[2])
+		if err != nil {
+			return error_response(new_error(CodeInternal, "An error occurred while retrieving certificates"))
+		}
+		everything.Certificates = append(everything.Certificates, certificate)
+	}
+
+	everythingAsBytes, _ := json.Marshal(everything) //convert to array of bytes
+	return shim.Success(everythingAsBytes)
+}
@@ -0,0 +1,104 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// unit - an init_unit payload of u1
+func unit(id string, parent string, kind string) string {
+	return `{"id": "` + id + `", "university_id": "u1", "parent_id": "` + parent + `", "kind": "` + kind + `", "name": "Unit ` + id + `", "city": "Sao Paulo"}`
+}
+
+func TestUnitHierarchy(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	tests := []struct {
+		name    string
+		caller  string
+		payload string
+		code    string
+	}{
+		{"campus by a stranger", "Org9MSP:mallory", unit("centro", "", "campus"), CodeUnauthorized},
+		{"campus", "Org1MSP:joao", unit("centro", "", "campus"), ""},
+		{"second campus", "Org1MSP:joao", unit("norte", "", "campus"), ""},
+		{"faculty of a campus", "Org1MSP:joao", unit("eng", "centro", "faculty"), ""},
+		{"department of a faculty", "Org1MSP:joao", unit("eng-civil", "eng", "department"), ""},
+		{"taken id", "Org1MSP:joao", unit("eng", "centro", "faculty"), CodeAlreadyExists},
+		{"campus of a faculty", "Org1MSP:joao", unit("sul", "eng", "campus"), CodeInvalidArgument},
+		{"faculty of a department", "Org1MSP:joao", unit("law", "eng-civil", "faculty"), CodeInvalidArgument},
+		{"faculty of an unknown campus", "Org1MSP:joao", unit("law", "leste", "faculty"), CodeNotFound},
+		{"unknown kind", "Org1MSP:joao", unit("lab", "", "laboratory"), CodeInvalidArgument},
+		{"officer without a name", "Org1MSP:joao", strings.Replace(unit("law", "centro", "faculty"), `"city"`, `"officers": [{"role": "coordinator"}], "city"`, 1), CodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mspId, cn := split_identity(test.caller)
+			res := stub.as(mspId, cn).invoke("init_unit", test.payload)
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+
+	chain, err := get_unit_chain(stub, "u1", "eng-civil")
+	if err != nil || strings.Join(chain, ",") != "eng-civil,eng,centro" {
+		t.Fatalf("chain %v %v", chain, err)
+	}
+	var units []Unit
+	json.Unmarshal(ok(t, stub.as("Org9MSP", "anyone").invoke("read_units", "u1")), &units)
+	if len(units) != 4 {
+		t.Fatalf("units %+v", units)
+	}
+
+	fails(t, stub.as("Org1MSP", "joao").invoke("set_unit_officers", "u1", "eng", `{"role": "dean"}`), CodeInvalidArgument)
+	ok(t, stub.as("Org1MSP", "joao").invoke("set_unit_officers", "u1", "eng", `[{"role": "coordinator", "name": "Maria", "identity": "Org1MSP:maria"}]`))
+	faculty, _ := get_unit(stub, "u1", "eng")
+	if len(faculty.Officers) != 1 || faculty.Officers[0].Name != "Maria" {
+		t.Fatalf("officers %+v", faculty.Officers)
+	}
+}
+
+func TestUnitsScopeIssuance(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	for _, payload := range []string{unit("centro", "", "campus"), unit("norte", "", "campus"), unit("eng", "centro", "faculty")} {
+		ok(t, stub.as("Org1MSP", "joao").invoke("init_unit", payload))
+	}
+	ok(t, stub.as("Org1MSP", "joao").invoke("grant_delegation", `{"id": "d1", "university_id": "u1", "registrar": "Org1MSP:registrar", "campuses": ["centro"], "valid_from": "2017-08-01", "valid_until": "2017-12-31"}`))
+
+	//a delegation for a campus covers the units below it
+	fails(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1", "unit_id": "norte"}), CodeUnauthorized)
+	fails(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1", "unit_id": "leste"}), CodeNotFound)
+	ok(t, stub.issue_as("Org1MSP", "registrar", map[string]interface{}{"id": "c1", "delegation_id": "d1", "unit_id": "eng"}))
+	ok(t, stub.issue(map[string]interface{}{"id": "c2", "unit_id": "norte"}))
+
+	fails(t, stub.as("Org9MSP", "mallory").invoke("read_unit_certificates", "u1", "eng"), CodeUnauthorized)
+	var everything struct {
+		Certificates []Certificate `json:"certificates"`
+	}
+	json.Unmarshal(ok(t, stub.as("Org1MSP", "joao").invoke("read_unit_certificates", "u1", "eng")), &everything)
+	if len(everything.Certificates) != 1 || everything.Certificates[0].Id != "c1" {
+		t.Fatalf("certificates of eng %+v", everything.Certificates)
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package vc builds, signs and verifies W3C Verifiable Credentials.
+//
+// It has no chaincode dependencies so issuers, wallets and verifiers can use it off-chain, the chaincode only
+// builds unsigned credentials and verifies signed ones. Credentials are signed as VC-JWT (VC Data Model 1.1,
+// section 6.3.1): a compact ES256 JWS whose "vc" claim holds the credential, with iss, jti, nbf and sub taken
+// from it. Any JOSE library can check the signature, no JSON-LD canonicalization is involved.
+package vc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	ContextV1      = "https://www.w3.org/2018/credentials/v1"
+	TypeCredential = "VerifiableCredential"
+	Algorithm      = "ES256" //JWS algorithm of every signed credential
+)
+
+// ----- JWT Header ----- //
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid,omitempty"` //verification method of the issuer
+}
+
+// ----- JWT Claims ----- //
+// the registered claims mirror the credential, VC Data Model 1.1 section 6.3.1
+type jwtClaims struct {
+	Issuer     string     `json:"iss"`
+	Id         string     `json:"jti"`
+	NotBefore  int64      `json:"nbf"`
+	Subject    string     `json:"sub,omitempty"`
+	Credential Credential `json:"vc"`
+}
+
+// ----- Credential ----- //
+type Credential struct {
+	Context           []string               `json:"@context"`
+	Id                string                 `json:"id"`
+	Type              []string               `json:"type"`
+	Issuer            Issuer                 `json:"issuer"`
+	IssuanceDate      string                 `json:"issuanceDate"`      //RFC3339
+	CredentialSubject map[string]interface{} `json:"credentialSubject"` //claims about the subject, shape depends on the credential type
+	CredentialStatus  *Status                `json:"credentialStatus,omitempty"`
+	CredentialSchema  []Schema               `json:"credentialSchema,omitempty"` //e.g. the SHACL shapes of Europass
+}
+
+// ----- Issuer ----- //
+type Issuer struct {
+	Id       string    `json:"id"`
+	Type     []string  `json:"type,omitempty"` //e.g. "Profile" for Open Badges
+	Name     string    `json:"name"`
+	Location *Location `json:"location,omitempty"` //e.g. for Europass
+}
+
+// ----- Location ----- //
+type Location struct {
+	CountryCode string `json:"countryCode"` //country URI
+}
+
+// ----- Schema ----- //
+type Schema struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ----- Status ----- //
+type Status struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ========================================================
+// New - an unsigned credential, types are added after "VerifiableCredential"
+// ========================================================
+func New(id string, types []string, issuer Issuer, issued time.Time, subject map[string]interface{}) Credential {
+	return Credential{
+		Context:           []string{ContextV1},
+		Id:                id,
+		Type:              append([]string{TypeCredential}, types...),
+		Issuer:            issuer,
+		IssuanceDate:      issued.UTC().Format(time.RFC3339),
+		CredentialSubject: subject,
+	}
+}
+
+// ========================================================
+// Claims - the JWT claims of a credential
+// ========================================================
+func (c Credential) claims() (jwtClaims, error) {
+	var claims jwtClaims
+	issued, err := time.Parse(time.RFC3339, c.IssuanceDate)
+	if err != nil {
+		return claims, errors.New("vc: issuanceDate is not RFC3339 - " + c.IssuanceDate)
+	}
+	claims.Issuer = c.Issuer.Id
+	claims.Id = c.Id
+	claims.NotBefore = issued.Unix()
+	claims.Subject, _ = c.CredentialSubject["id"].(string)
+	claims.Credential = c
+	return claims, nil
+}
+
+// ========================================================
+// Sign - the credential as a VC-JWT signed with the issuer's P-256 key
+//
+// keyId names the verification method of the issuer, e.g. "<issuer id>#key-1", and goes in the kid header.
+// ========================================================
+func Sign(c Credential, key *ecdsa.PrivateKey, keyId string) (string, error) {
+	if key.Curve != elliptic.P256() {
+		return "", errors.New("vc: ES256 needs a P-256 key")
+	}
+	claims, err := c.claims()
+	if err != nil {
+		return "", err
+	}
+	headerAsBytes, _ := json.Marshal(jwtHeader{Algorithm: Algorithm, Type: "JWT", KeyId: keyId})
+	claimsAsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerAsBytes) + "." + base64.RawURLEncoding.EncodeToString(claimsAsBytes)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	rAsBytes, sAsBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rAsBytes):32], rAsBytes) //left pad both to 32 bytes, RFC 7518 section 3.4
+	copy(signature[64-len(sAsBytes):], sAsBytes)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ========================================================
+// Decode - the credential of a VC-JWT, without checking its signature
+//
+// The registered claims must agree with the credential, a token whose iss, jti, nbf or sub say otherwise is refused.
+// ========================================================
+func Decode(token string) (Credential, error) {
+	var claims jwtClaims
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return claims.Credential, errors.New("vc: not a compact JWT")
+	}
+	var header jwtHeader
+	headerAsBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(headerAsBytes, &header) != nil {
+		return claims.Credential, errors.New("vc: malformed JWT header")
+	}
+	if header.Algorithm != Algorithm {
+		return claims.Credential, errors.New("vc: unsupported JWT algorithm " + header.Algorithm)
+	}
+	claimsAsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(claimsAsBytes, &claims) != nil {
+		return claims.Credential, errors.New("vc: malformed JWT claims")
+	}
+
+	c := claims.Credential
+	if len(c.Context) == 0 || c.Context[0] != ContextV1 {
+		return c, errors.New("vc: first @context must be " + ContextV1)
+	}
+	expected, err := c.claims()
+	if err != nil {
+		return c, err
+	}
+	if claims.Issuer != expected.Issuer || claims.Id != expected.Id || claims.NotBefore != expected.NotBefore || claims.Subject != expected.Subject {
+		return c, errors.New("vc: JWT claims do not match the credential")
+	}
+	return c, nil
+}
+
+// ========================================================
+// Verify - check a VC-JWT against the issuer's public key, returns its credential
+// ========================================================
+func Verify(token string, key *ecdsa.PublicKey) (Credential, error) {
+	c, err := Decode(token)
+	if err != nil {
+		return c, err
+	}
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return c, errors.New("vc: malformed JWT signature")
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return c, errors.New("vc: JWT signature does not match")
+	}
+	return c, nil
+}
+
+// ========================================================
+// Parse - decode a credential from JSON
+// ========================================================
+func Parse(credentialAsBytes []byte) (Credential, error) {
+	var c Credential
+	err := json.Unmarshal(credentialAsBytes, &c)
+	if err != nil {
+		return c, errors.New("vc: not a credential - " + err.Error())
+	}
+	if len(c.Context) == 0 || c.Context[0] != ContextV1 {
+		return c, errors.New("vc: first @context must be " + ContextV1)
+	}
+	if len(c.Type) == 0 || c.Type[0] != TypeCredential {
+		return c, errors.New("vc: first type must be " + TypeCredential)
+	}
+	return c, nil
+}
+
+// ========================================================
+// Parse Public Key - a PEM encoded P-256 public key
+// ========================================================
+func ParsePublicKey(pemAsBytes []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(pemAsBytes)
+	if block == nil {
+		return nil, errors.New("vc: public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("vc: cannot parse public key - " + err.Error())
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("vc: public key is not a P-256 key")
+	}
+	return ecKey, nil
+}
+
+// ========================================================
+// Parse Private Key - a PEM encoded P-256 private key, SEC 1 or PKCS #8
+// ========================================================
+func ParsePrivateKey(pemAsBytes []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemAsBytes)
+	if block == nil {
+		return nil, errors.New("vc: private key is not PEM encoded")
+	}
+	ecKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New("vc: cannot parse private key - " + err.Error())
+		}
+		var ok bool
+		if ecKey, ok = key.(*ecdsa.PrivateKey); !ok {
+			return nil, errors.New("vc: private key is not an ECDSA key")
+		}
+	}
+	if ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("vc: private key is not a P-256 key")
+	}
+	return ecKey, nil
+}
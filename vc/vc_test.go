@@ -0,0 +1,108 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package vc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	issued := time.Date(2017, 8, 4, 12, 0, 0, 0, time.UTC)
+	credential := New("urn:aion:ch:certificate:c1", []string{"UniversityDegreeCredential"}, Issuer{Id: "urn:aion:ch:university:u1", Name: "Uni"}, issued,
+		map[string]interface{}{"id": "urn:aion:ch:graduate:g1", "name": "Maria"})
+	token, err := Sign(credential, key, "urn:aion:ch:university:u1#key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	//resign a token whose claims were changed
+	resign := func(change func(claims map[string]interface{})) string {
+		claimsAsBytes, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]interface{}
+		json.Unmarshal(claimsAsBytes, &claims)
+		change(claims)
+		claimsAsBytes, _ = json.Marshal(claims)
+		signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claimsAsBytes)
+		digest := sha256.Sum256([]byte(signingInput))
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	tampered := func(change func(claims map[string]interface{})) string {
+		claimsAsBytes, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]interface{}
+		json.Unmarshal(claimsAsBytes, &claims)
+		change(claims)
+		claimsAsBytes, _ = json.Marshal(claims)
+		return parts[0] + "." + base64.RawURLEncoding.EncodeToString(claimsAsBytes) + "." + parts[2]
+	}
+	subject := func(claims map[string]interface{}) map[string]interface{} {
+		return claims["vc"].(map[string]interface{})["credentialSubject"].(map[string]interface{})
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		key   *ecdsa.PublicKey
+		valid bool
+	}{
+		{"untouched", token, &key.PublicKey, true},
+		{"other key", token, &other.PublicKey, false},
+		{"subject changed", tampered(func(claims map[string]interface{}) { subject(claims)["name"] = "Mario" }), &key.PublicKey, false},
+		{"issuer claim disagrees", resign(func(claims map[string]interface{}) { claims["iss"] = "urn:aion:ch:university:u2" }), &key.PublicKey, false},
+		{"nbf disagrees", resign(func(claims map[string]interface{}) { claims["nbf"] = 0 }), &key.PublicKey, false},
+		{"sub disagrees", resign(func(claims map[string]interface{}) { subject(claims)["id"] = "urn:aion:ch:graduate:g2" }), &key.PublicKey, false},
+		{"other algorithm", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".", &key.PublicKey, false},
+		{"not a JWT", "{}", &key.PublicKey, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verified, err := Verify(test.token, test.key)
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the token to be rejected")
+			}
+			if test.valid && (verified.Id != credential.Id || verified.CredentialSubject["name"] != "Maria") {
+				t.Fatalf("verified %+v", verified)
+			}
+		})
+	}
+
+	//the header names the algorithm and the key, as JOSE libraries expect
+	headerAsBytes, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if string(headerAsBytes) != `{"alg":"ES256","typ":"JWT","kid":"urn:aion:ch:university:u1#key-1"}` {
+		t.Fatalf("header %s", headerAsBytes)
+	}
+}
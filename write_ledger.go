@@ -20,130 +20,198 @@ under the License.
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
+	"time"
 )
 
 // ============================================================================================================================
-// write() - generic write variable into ledger
+// Init Certificate - create a new certificate, store into chaincode state
+//
+// Shows off building key's value from GoLang Structure.
+//
+// Must be submitted by the dean in office, or by a registrar naming a delegation from them in delegation_id.
+// The dean term and the delegation used are recorded on the certificate.
+//
+// The document must be a valid CPF, or a passport number when document_type is "passport", and is stored in
+// canonical form. The date, YYYY-MM-DD, ISO-8601 or 13 digit epoch milliseconds, is stored as YYYY-MM-DD and must respect
+// the date policy of the config, the transaction time is recorded as issuedAt.
+//
+// The document is stored in a restricted record, apart from the public certificate. Issuers never hold the graduate
+// key, an admin links the certificate to its graduate afterwards with link_certificate_graduate.
+//
+// Inputs - JSON payload validated against certificate_schema
+//
+//	{
+//		"id": "c123",
+//		"name": "José",
+//		"document": "529.982.247-25",
+//		"document_type": "cpf",
+//		"body": "Certificate...",
+//		"city": "Sao Paulo",
+//		"date": "2017-08-04",
+//		"university_id": "u123",
+//		"university_doc": "11.222.333/0001-81",
+//		"delegation_id": "d123",
+//		"unit_id": "fac-eng",
+//		"program_id": "p123"
+//	}
+//
+// Deprecated - Array of strings, adapted to the payload above, the required program is the 9th argument. The 8
+// argument form from before programs is still read, it fails validation with program_id reported missing.
 //
-// Shows Off PutState() - writing a key/value into the ledger
+//	 0    | 1      |  2               | 3                | 4           | 5               | 6             | 7                    | 8
+//	id    | name   |  document        | body             | city        | date            | university_id | university_doc       | program_id
 //
-// Inputs - Array of strings
-//    0   ,    1
-//   key  ,  value
-//  "abc" , "test"
+// "c123" | "José" |  "529.982.247-25" | "Certificate..." | "Sao Paulo" | "1501810298042" | "u123"        | "11.222.333/0001-81" | "p123"
 // ============================================================================================================================
-func write(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var key, value string
+func init_cert(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
-	fmt.Println("starting write")
+	fmt.Println("starting init_cert")
 
-	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2. key of the variable and value to set")
+	//input sanitation
+	var payload CertificatePayload
+	err = parse_payload(args, certificate_schema, &payload)
+	if err != nil {
+		return error_response(err)
 	}
 
-	// input sanitation
-	err = sanitize_arguments(args)
+	//check the graduate document, it is stored in its canonical form
+	document_type := payload.DocumentType
+	if len(document_type) == 0 {
+		document_type = "cpf"
+	}
+	document, err := canonical_document(document_type, payload.Document)
 	if err != nil {
-		return shim.Error(err.Error())
+		field := "document"
+		if !document_types[document_type] {
+			field = "document_type"
+		}
+		return error_response(ValidationError{Schema: certificate_schema.Name, Fields: []FieldError{{Field: field, Message: error_message(err)}}})
 	}
 
-	key = args[0] //rename for funsies
-	value = args[1]
-	err = stub.PutState(key, []byte(value)) //write the variable into the ledger
+	//the conferral date is stored as YYYY-MM-DD whatever the client sent
+	date, err := canonical_date(payload.Date)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(ValidationError{Schema: certificate_schema.Name, Fields: []FieldError{{Field: "date", Message: error_message(err)}}})
 	}
 
-	fmt.Println("- end write")
-	return shim.Success(nil)
-}
+	id := payload.Id
+	name := payload.Name
+	body := payload.Body
+	city := payload.City
+	university_id := payload.UniversityId
+	university_doc := payload.UniversityDoc
 
-// ============================================================================================================================
-// Init Certificate - create a new certificate, store into chaincode state
-//
-// Shows off building a key's JSON value manually
-//
-// Inputs - Array of strings
-//   0    | 1      |  2        | 3                | 4           | 5               | 6             | 7
-//  id    | name   |  document | body             | city        | date            | university_id | university_doc
-// "c123" | "José" |  "123456" | "Certificate..." | "Sao Paulo" | "1501810298042" | "u123"        | "456789"
-// ============================================================================================================================
-func init_cert(stub shim.ChaincodeStubInterface, args []string) (pb.Response) {
-	var err error
-	fmt.Println("starting init_cert")
+	//check if university exists
+	university, err := get_university(stub, university_id)
+	if err != nil {
+		fmt.Println("Failed to find university - " + university_id)
+		return error_response(err)
+	}
 
-	if len(args) != 8 {
-		return shim.Error("Incorrect number of arguments. Expecting 8")
+	//check university document
+	if !same_cnpj(university.Document, university_doc) {
+		return error_response(new_error(CodeUnauthorized, "The university '"+university.UniversityName+"' cannot authorize creation for university '"+university_doc+"'."))
 	}
 
-	//input sanitation
-	err = sanitize_arguments(args)
+	//check the issuing unit belongs to the university
+	var units []string
+	if len(payload.UnitId) > 0 {
+		units, err = get_unit_chain(stub, university_id, payload.UnitId)
+		if err != nil {
+			return error_response(err)
+		}
+	}
+
+	//check the submitter is the dean in office or one of their registrars
+	signer, err := authorize_issuance(stub, university, payload.DelegationId, payload.ProgramId, units)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
-	id := args[0]
-	name := args[1]
-	document := args[2]
-	body := args[3]
-	city := args[4]
-	date := args[5]
-	university_id := args[6]
-	university_doc := args[7]
+	//check the university may still issue
+	if university.Status != "active" {
+		return error_response(new_error(CodeConflict, "University '"+university_id+"' is "+university.Status+" and cannot issue certificates"))
+	}
 
-	//check if university exists
-	university, err := get_university(stub, university_id)
+	//check the university is accredited at issuance
+	now, err := get_tx_time(stub)
 	if err != nil {
-		fmt.Println("Failed to find university - " + university_id)
-		return shim.Error(err.Error())
+		return error_response(err)
+	}
+	err = check_accreditation(stub, university_id, now)
+	if err != nil {
+		return error_response(err)
 	}
 
-	//check university document
-	if university.Document != university_doc {
-		return shim.Error("The university '" + university.UniversityName + "' cannot authorize creation for university '" + university_doc + "'.")
+	//check the conferral date is not too far from the issuance
+	config, err := get_config(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	err = check_date_policy(config.DatePolicy, date, now)
+	if err != nil {
+		return error_response(err)
 	}
 
-	//check if certificate id already exists
-	certificate, err := get_certificate(stub, id)
-	if err == nil {
+	//check the program is one of the university's and recognized at issuance
+	_, err = check_program_at(stub, university_id, payload.ProgramId, now)
+	if err != nil {
+		return error_response(err)
+	}
+
+	//check if the id is free, any record under it would be overwritten
+	existingAsBytes, err := stub.GetState(id)
+	if err != nil {
+		return error_response(wrap_error("Failed to get certificate", err))
+	}
+	if existingAsBytes != nil {
 		fmt.Println("This certificate already exists - " + id)
-		fmt.Println(certificate)
-		return shim.Error("This certificate already exists - " + id) //all stop a certificate by this id exists
-	}
-
-	//build the certificate json string manually
-	str := `{
-		"docType":"certificate",
-		"id": "` + id + `",
-		"name": "` + name + `",
-		"document": ` + document + `,
-		"body": ` + body + `,
-		"city": ` + city + `,
-		"date": ` + date + `,
-		"university": {
-			"id": "` + university_id + `",
-			"dean": "` + university.Dean + `",
-			"name": "` + university.UniversityName + `"
-		}
-	}`
-	err = stub.PutState(id, []byte(str)) //store certificate with id as key
+		return error_response(new_error(CodeAlreadyExists, "A record already exists under the certificate id - "+id)) //all stop a record by this id exists
+	}
+
+	//build the certificate from its typed fields, every value is escaped by encoding/json
+	var certificate Certificate
+	certificate.ObjectType = "certificate"
+	certificate.SchemaVersion = CertificateSchemaVersion
+	certificate.Id = id
+	certificate.Name = name
+	certificate.Document = document
+	certificate.DocumentType = document_type
+	certificate.Body = body
+	certificate.City = city
+	certificate.Date = date
+	certificate.University = UniversityRelation{Id: university_id, Dean: university.Dean, Name: university.UniversityName}
+	certificate.IssuedBy = university_id
+	certificate.DeanTerm = signer.Term.Id
+	certificate.SignedBy = signer.Identity
+	certificate.Delegation = signer.Delegation
+	certificate.UnitId = payload.UnitId
+	certificate.ProgramId = payload.ProgramId
+	certificate.IssuedAt = now.Format(time.RFC3339Nano)
+	err = put_certificate(stub, certificate)
 	if err != nil {
 		fmt.Println("Could not store certificate")
-		return shim.Error(err.Error())
+		return error_response(err)
+	}
+
+	//list it under its unit
+	if len(certificate.UnitId) > 0 {
+		err = index_certificate_unit(stub, university_id, certificate.UnitId, id)
+		if err != nil {
+			return error_response(err)
+		}
 	}
 
 	//add current certificate to known certificates
 	university.Certificates = append(university.Certificates, id)
 	//store university
-	universityAsBytes, _ := json.Marshal(university)      //convert to array of bytes
-	err = stub.PutState(university.Id, universityAsBytes) //store university by its Id
+	err = put_asset(stub, university.Id, &university) //store university by its Id
 	if err != nil {
 		fmt.Println("Could not store university")
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
 	fmt.Println("- end init_certificate")
@@ -151,121 +219,245 @@ func init_cert(stub shim.ChaincodeStubInterface, args []string) (pb.Response) {
 }
 
 // ============================================================================================================================
-// Init University - create a new university, store into chaincode state
+// Init University - create a new university, store into chaincode state, accreditors only
 //
-// Shows off building key's value from GoLang Structure
+// Shows off building key's value from GoLang Structure.
 //
-//type University struct {
-//	ObjectType     string `json:"docType"`        //field for couchdb
-//	Id             string `json:"id"`             //UUID
-//	Dean           string `json:"dean"`           //dean of university (reitor)
-//	UniversityName string `json:"universityname"` //Name of university
-//	Document       string `json:"document"`       //University national document (cnpj)
-//}
-// Inputs - Array of Strings
+//	type University struct {
+//		ObjectType     string `json:"docType"`        //field for couchdb
+//		Id             string `json:"id"`             //UUID
+//		Dean           string `json:"dean"`           //dean of university (reitor)
+//		UniversityName string `json:"universityname"` //Name of university
+//		Document       string `json:"document"`       //University national document (cnpj)
+//	}
+//
+// Inputs - JSON payload validated against university_schema
+//
+//	{
+//		"id": "u123",
+//		"dean": "joao",
+//		"dean_identity": "Org1MSP:joao@uniuni.edu.br",
+//		"name": "uniuni",
+//		"document": "11.222.333/0001-81",
+//		"municipality": "Sao Paulo"
+//	}
+//
+// Deprecated - Array of Strings, adapted to the payload above
 // 0      | 1      | 2        | 3
 // id     | dean   | name     | document
-// "u123" | "joao" | "uniuni" | "123456"
+// "u123" | "joao" | "uniuni" | "11.222.333/0001-81"
 // ============================================================================================================================
 func init_university(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	fmt.Println("starting init_university")
 
-	if len(args) != 4 {
-		return shim.Error("Incorrect number of arguments. Expecting 4")
+	//with consortium governance enabled universities are admitted by proposal only
+	config, err := get_config(stub)
+	if err != nil {
+		return error_response(err)
+	}
+	if config.Features["governance"] {
+		return error_response(new_error(CodeConflict, "Universities are admitted by consortium vote, submit an admit_university proposal instead"))
+	}
+
+	//only accreditors may register universities
+	_, err = get_creator_accreditor(stub)
+	if err != nil {
+		return error_response(err)
 	}
 
 	//input sanitation
-	err = sanitize_arguments(args)
+	var payload UniversityPayload
+	err = parse_payload(args, university_schema, &payload)
+	if err != nil {
+		return error_response(err)
+	}
+
+	err = create_university(stub, payload)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
+	fmt.Println("- end init_university")
+	return shim.Success(nil)
+}
+
+// ========================================================
+// Create University - build a university from a validated payload and store it
+// ========================================================
+func create_university(stub shim.ChaincodeStubInterface, payload UniversityPayload) error {
 	var university University
+	cnpj, err := check_university_cnpj(stub, payload.Document)
+	if err != nil {
+		return err
+	}
 	university.ObjectType = "university"
-	university.Id = args[0]
-	university.Dean = args[1]
-	university.UniversityName = args[2]
-	university.Document = args[3]
-	fmt.Println(university)
+	university.SchemaVersion = UniversitySchemaVersion
+	university.Id = payload.Id
+	university.Dean = payload.Dean
+	university.UniversityName = payload.Name
+	university.Document = cnpj
+	university.Municipality = payload.Municipality
+	university.Status = "active"
 
 	//check if university already exists
 	_, err = get_university(stub, university.Id)
 	if err == nil {
 		fmt.Println("This university already exists - " + university.Id)
-		return shim.Error("This university already exists - " + university.Id)
+		return new_error(CodeAlreadyExists, "This university already exists - "+university.Id)
+	}
+
+	//the first dean term starts with the university
+	if len(payload.DeanIdentity) > 0 {
+		err = check_identity_format(payload.DeanIdentity)
+		if err != nil {
+			return err
+		}
+	}
+	err = start_dean_term(stub, &university, payload.Dean, payload.DeanIdentity)
+	if err != nil {
+		return err
 	}
 
 	//store university
-	universityAsBytes, _ := json.Marshal(university)      //convert to array of bytes
-	err = stub.PutState(university.Id, universityAsBytes) //store university by its Id
+	err = put_asset(stub, university.Id, &university) //store university by its Id
 	if err != nil {
 		fmt.Println("Could not store university")
-		return shim.Error(err.Error())
+		return err
 	}
-
-	fmt.Println("- end init_university")
-	return shim.Success(nil)
+	return index_university_cnpj(stub, university)
 }
 
 // ============================================================================================================================
-// Set Dean on University
+// Set Dean on University - end the current dean term and start one for the new dean
 //
 // Shows off GetState() and PutState()
 //
+// Only the current dean or the accreditor of record may change the dean. Without new_dean_identity the new dean
+// cannot sign certificates until a later term registers one.
+//
 // Inputs - Array of Strings
-//  0             | 1        | 2
-//  university_id | old_dean | new_dean
-// "u123"         | "Joao"   | "Jose"
+//
+//	0             | 1        | 2        | 3
+//	university_id | old_dean | new_dean | new_dean_identity (optional)
+//
+// "u123"         | "Joao"   | "Jose"   | "Org1MSP:jose@uniuni.edu.br"
 // ============================================================================================================================
 func set_dean(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	var err error
 	var university University
-	fmt.Println("starting set_owner")
+	fmt.Println("starting set_dean")
 
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
+	if len(args) != 3 && len(args) != 4 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 3 or 4"))
 	}
 
 	// input sanitation
 	err = sanitize_arguments(args)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
 	var university_id = args[0]
 	var old_dean = args[1]
 	var new_dean = args[2]
+	var new_dean_identity string
+	if len(args) == 4 {
+		new_dean_identity = args[3]
+		err = check_identity_format(new_dean_identity)
+		if err != nil {
+			return error_response(err)
+		}
+	}
 	fmt.Println(university_id + "->" + old_dean + " - |" + new_dean)
 
 	// check if new_dean is equals to old_dean
 	if old_dean == new_dean {
-		return shim.Error("Old dean (" + old_dean + ") is equal to new dean (" + new_dean + ")")
+		return error_response(new_error(CodeInvalidArgument, "Old dean ("+old_dean+") is equal to new dean ("+new_dean+")"))
 	}
 
 	// retrieves university
 	university, err = get_university(stub, university_id)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
 	}
 
 	// check if provided old dean is diferent from state saved current dean
 	if university.Dean != old_dean {
-		return shim.Error("Provided old dean isn't the current dean")
+		return error_response(new_error(CodeInvalidArgument, "Provided old dean isn't the current dean"))
 	}
 	// check if provided new dean is equal to state saved current dean
 	if university.Dean == new_dean {
-		return shim.Error("Provided new dean is the current dean")
+		return error_response(new_error(CodeInvalidArgument, "Provided new dean is the current dean"))
+	}
+
+	// only the current dean or the accreditor of record may hand over the mandate
+	_, err = require_signer(stub, university)
+	if err != nil {
+		_, err = require_accreditor_of(stub, university_id)
+		if err != nil {
+			return error_response(wrap_error("Only the current dean or the accreditor of record may change the dean", err))
+		}
 	}
 
 	// change dean
-	university.Dean = new_dean
-	jsonAsBytes, _ := json.Marshal(university) //convert to array of bytes
-	err = stub.PutState(args[0], jsonAsBytes)  //rewrite the university with id as key
+	err = start_dean_term(stub, &university, new_dean, new_dean_identity)
 	if err != nil {
-		return shim.Error(err.Error())
+		return error_response(err)
+	}
+	err = put_asset(stub, args[0], &university) //rewrite the university with id as key
+	if err != nil {
+		return error_response(err)
 	}
 
 	fmt.Println("- end set dean")
 	return shim.Success(nil)
 }
+
+// ============================================================================================================================
+// Set University Municipality - set the municipality of the seat of the university, dean or accreditor of record
+//
+// Diplomas name this municipality for the issuing university, not the city the certificate was emitted in.
+//
+// Inputs - Array of Strings
+//
+//	0             | 1
+//	university_id | municipality
+//	"u123"        | "Sao Paulo"
+//
+// ============================================================================================================================
+func set_university_municipality(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
+	fmt.Println("starting set_university_municipality")
+
+	if len(args) != 2 {
+		return error_response(new_error(CodeInvalidArgument, "Incorrect number of arguments. Expecting 2"))
+	}
+
+	// input sanitation
+	err = sanitize_arguments(args)
+	if err != nil {
+		return error_response(err)
+	}
+
+	university, err := get_university(stub, args[0])
+	if err != nil {
+		return error_response(err)
+	}
+	_, err = require_signer(stub, university)
+	if err != nil {
+		_, err = require_accreditor_of(stub, university.Id)
+		if err != nil {
+			return error_response(wrap_error("Only the current dean or the accreditor of record may set the municipality", err))
+		}
+	}
+
+	university.Municipality = args[1]
+	err = put_asset(stub, university.Id, &university) //rewrite the university with id as key
+	if err != nil {
+		return error_response(err)
+	}
+
+	fmt.Println("- end set_university_municipality")
+	return shim.Success(nil)
+}
@@ -0,0 +1,137 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestInitCertRefusesTakenKeys(t *testing.T) {
+	stub := new_issuing_ledger(t)
+	ok(t, stub.issue(map[string]interface{}{"id": "c1"}))
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"certificate", "c1"},
+		{"university", "u1"},
+		{"raw value", "legacy"},
+	}
+	stub.put("legacy", []byte("not json"))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, _ := stub.GetState(test.id)
+			fails(t, stub.issue(map[string]interface{}{"id": test.id}), CodeAlreadyExists)
+			after, _ := stub.GetState(test.id)
+			if string(before) != string(after) {
+				t.Fatalf("%s was overwritten", test.id)
+			}
+		})
+	}
+}
+
+func TestIssuanceUniversityDoc(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		code string
+	}{
+		{"punctuated", test_cnpj, ""},
+		{"bare", "11222333000181", ""},
+		{"of another university", "11.444.777/0001-61", CodeUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := new_issuing_ledger(t)
+			res := stub.issue(map[string]interface{}{"university_doc": test.doc})
+			if test.code != "" {
+				fails(t, res, test.code)
+				return
+			}
+			ok(t, res)
+		})
+	}
+}
+
+// FuzzInitCert - no content of the free text fields may change any other field of the stored certificate
+func FuzzInitCert(f *testing.F) {
+	f.Add("Maria", "Bachelor of Computer Science", "Sao Paulo")
+	f.Add(`Maria", "university": {"id": "u2"}, "x": "`, `", "docType": "university`, "Sao Paulo")
+	f.Add("Maria", `{"id": "c2", "issuedBy": "u2"}`, `\", \"graduateId\": \"g1`)
+	f.Add("\x00graduate\x00g1\x00", "body\n\t ", "<script>")
+	f.Fuzz(func(t *testing.T, name string, body string, city string) {
+		if !utf8.ValidString(name) || !utf8.ValidString(body) || !utf8.ValidString(city) {
+			t.Skip("JSON payloads carry UTF-8 only")
+		}
+		stub := new_issuing_ledger(t)
+		res := stub.issue(map[string]interface{}{"name": name, "body": body, "city": city})
+		if res.Status != 200 {
+			fails(t, res, CodeInvalidArgument)
+			return
+		}
+
+		var fields map[string]interface{}
+		certificateAsBytes, _ := stub.GetState("c1")
+		json.Unmarshal(certificateAsBytes, &fields)
+		if _, found := fields["document"]; found {
+			t.Fatalf("public certificate carries the document - %s", certificateAsBytes)
+		}
+		certificate, err := get_certificate(stub, "c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if certificate.Name != name || certificate.Body != body || certificate.City != city {
+			t.Fatalf("free text fields were altered - %q %q %q", certificate.Name, certificate.Body, certificate.City)
+		}
+		if certificate.ObjectType != "certificate" || certificate.Id != "c1" || certificate.University.Id != "u1" || certificate.IssuedBy != "u1" ||
+			certificate.ProgramId != "p1" || certificate.Date != "2017-07-20" || certificate.Document != "52998224725" || certificate.DeanTerm == "" {
+			t.Fatalf("fixed fields were altered - %s", certificateAsBytes)
+		}
+		expected, _ := json.Marshal(public_certificate(certificate))
+		var expectedFields map[string]interface{}
+		json.Unmarshal(expected, &expectedFields)
+		if len(fields) != len(expectedFields) {
+			t.Fatalf("stored certificate has extra fields - %s", certificateAsBytes)
+		}
+	})
+}
+
+func TestInitCertPositionalForm(t *testing.T) {
+	stub := new_issuing_ledger(t)
+
+	//the form from before programs is read, and names what it lacks
+	missing := fails(t, stub.invoke("init_cert", "c1", "Maria", test_cpf, "Bachelor", "Sao Paulo", "2017-07-20", "u1", test_cnpj), CodeInvalidArgument)
+	if len(missing.Fields) != 1 || missing.Fields[0].Field != "program_id" {
+		t.Fatalf("the 8 argument form reported %+v", missing)
+	}
+	fails(t, stub.invoke("init_cert", "c1", "Maria", test_cpf, "Bachelor", "Sao Paulo", "2017-07-20", "u1"), CodeInvalidArgument)
+	ok(t, stub.invoke("init_cert", "c1", "Maria", test_cpf, "Bachelor", "Sao Paulo", "2017-07-20", "u1", test_cnpj, "p1"))
+
+	certificate, err := get_certificate(stub, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certificate.ProgramId != "p1" || certificate.University.Id != "u1" || certificate.Body != "Bachelor" {
+		t.Fatalf("positional arguments landed in the wrong fields - %+v", certificate)
+	}
+}